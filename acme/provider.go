@@ -0,0 +1,401 @@
+// Package acme solves ACME DNS-01 challenges with Njalla TXT records.
+//
+// `DNSProvider` implements the `challenge.Provider` and
+// `challenge.ProviderTimeout` interfaces of lego
+// (github.com/go-acme/lego/v4/challenge), so it can be given to a lego
+// client as is:
+//
+//	provider, err := acme.NewDNSProvider()
+//	if err != nil {
+//		return err
+//	}
+//	client.Challenge.SetDNS01Provider(provider)
+//
+// Records are added through the same client, name handling and TXT encoding
+// the Terraform provider uses, and the `njalla_acme_challenge` resource is
+// built on this package.
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	lego "github.com/go-acme/lego/v4/challenge"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// Environment variables read by `NewDefaultConfig`. The token and endpoint
+// are the ones the Terraform provider reads.
+const (
+	EnvToken              = "NJALLA_API_TOKEN"
+	EnvEndpoint           = "NJALLA_API_ENDPOINT"
+	EnvTTL                = "NJALLA_TTL"
+	EnvPropagationTimeout = "NJALLA_PROPAGATION_TIMEOUT"
+	EnvPollingInterval    = "NJALLA_POLLING_INTERVAL"
+)
+
+// Defaults of a `Config`. Njalla's smallest TTL is used, so a stale answer
+// doesn't outlive the challenge.
+const (
+	DefaultTTL                = 60
+	DefaultPropagationTimeout = 5 * time.Minute
+	DefaultPollingInterval    = 5 * time.Second
+)
+
+// challengeLabel is the label challenge records are added under, as defined
+// in RFC 8555 section 8.4.
+const challengeLabel = "_acme-challenge"
+
+// Config configures a `DNSProvider`.
+type Config struct {
+	// Token is the Njalla API token.
+	Token string
+	// Endpoint is the Njalla API endpoint. Defaults to Njalla's.
+	Endpoint string
+	// HTTPClient sends the API requests. Defaults to a new `http.Client`.
+	HTTPClient *http.Client
+
+	// Zone is the Njalla domain challenge records are added to. When
+	// empty, it's the longest suffix of the challenge's domain that is a
+	// domain of the account.
+	Zone string
+	// TTL of the challenge records, in seconds.
+	TTL int
+
+	// PropagationTimeout and PollingInterval are returned by `Timeout`,
+	// for lego to wait for the records to propagate.
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+}
+
+// NewDefaultConfig returns a configuration from the environment, with the
+// defaults for anything unset. Durations are given in seconds, like the
+// configuration of lego's own providers.
+func NewDefaultConfig() (*Config, error) {
+	config := &Config{
+		Token:              os.Getenv(EnvToken),
+		Endpoint:           os.Getenv(EnvEndpoint),
+		TTL:                DefaultTTL,
+		PropagationTimeout: DefaultPropagationTimeout,
+		PollingInterval:    DefaultPollingInterval,
+	}
+
+	if err := envSeconds(EnvTTL, &config.TTL); err != nil {
+		return nil, err
+	}
+
+	for name, duration := range map[string]*time.Duration{
+		EnvPropagationTimeout: &config.PropagationTimeout,
+		EnvPollingInterval:    &config.PollingInterval,
+	} {
+		seconds := int(*duration / time.Second)
+		if err := envSeconds(name, &seconds); err != nil {
+			return nil, err
+		}
+		*duration = time.Duration(seconds) * time.Second
+	}
+
+	return config, nil
+}
+
+// envSeconds sets `seconds` from the environment variable `name`, if set.
+func envSeconds(name string, seconds *int) error {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(v)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf(
+			"njalla: expected %s to be a positive number of seconds, got: %s",
+			name, v,
+		)
+	}
+	*seconds = parsed
+
+	return nil
+}
+
+// DNSProvider adds and removes the TXT records of DNS-01 challenges.
+type DNSProvider struct {
+	client *api.Client
+	config *Config
+}
+
+var (
+	_ lego.Provider        = (*DNSProvider)(nil)
+	_ lego.ProviderTimeout = (*DNSProvider)(nil)
+)
+
+// NewDNSProvider returns a provider configured from the environment, as
+// described by `NewDefaultConfig`.
+func NewDNSProvider() (*DNSProvider, error) {
+	config, err := NewDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDNSProviderConfig(config)
+}
+
+// NewDNSProviderConfig returns a provider with its own API client.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("njalla: the configuration is missing")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("njalla: the API token is missing, set %s", EnvToken)
+	}
+
+	client := api.NewClient(config.Token)
+	if config.Endpoint != "" {
+		client.Endpoint = config.Endpoint
+	}
+	if config.HTTPClient != nil {
+		client.HTTPClient = config.HTTPClient
+	}
+
+	return NewDNSProviderClient(client, config), nil
+}
+
+// NewDNSProviderClient returns a provider making its calls through an
+// existing client, like the Terraform provider's, sharing its rate limiter.
+// The token, endpoint and HTTP client of `config` are ignored.
+func NewDNSProviderClient(client *api.Client, config *Config) *DNSProvider {
+	if config == nil {
+		config = &Config{}
+	}
+
+	return &DNSProvider{client: client, config: config}
+}
+
+// Timeout returns how long, and how often, lego should check the challenge
+// records propagated.
+func (p *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	timeout, interval = p.config.PropagationTimeout, p.config.PollingInterval
+	if timeout <= 0 {
+		timeout = DefaultPropagationTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultPollingInterval
+	}
+
+	return timeout, interval
+}
+
+// Present adds the TXT record of the challenge for `domain`.
+func (p *DNSProvider) Present(domain, token, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.callTimeout())
+	defer cancel()
+
+	_, err := p.PresentContext(ctx, domain, keyAuth)
+	return err
+}
+
+// CleanUp removes the TXT record of the challenge for `domain`.
+func (p *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.callTimeout())
+	defer cancel()
+
+	return p.CleanUpContext(ctx, domain, keyAuth)
+}
+
+// callTimeout bounds the API calls of `Present` and `CleanUp`, which lego
+// calls without a context.
+func (p *DNSProvider) callTimeout() time.Duration {
+	timeout, _ := p.Timeout()
+	return timeout
+}
+
+// Challenge is the TXT record of a DNS-01 challenge.
+type Challenge struct {
+	// FQDN is the fully qualified name of the record, with a trailing dot.
+	FQDN string
+	// Value is the text of the record.
+	Value string
+	// Zone is the Njalla domain the record is in.
+	Zone string
+	// Name is the name of the record relative to `Zone`.
+	Name string
+	// Record is the record as saved by Njalla.
+	Record gonjalla.Record
+}
+
+// ChallengeRecord returns the fully qualified name and text of the TXT
+// record answering the challenge for `domain` with the key authorization
+// `keyAuth`, as defined in RFC 8555 section 8.4. A leading `*.` is ignored,
+// as a wildcard is validated at its parent's name.
+func ChallengeRecord(domain, keyAuth string) (fqdn, value string) {
+	domain = strings.TrimPrefix(domain, "*.")
+	fqdn = dnsrecord.FQDN(challengeLabel, domain)
+
+	digest := sha256.Sum256([]byte(keyAuth))
+	value = base64.RawURLEncoding.EncodeToString(digest[:])
+
+	return fqdn, value
+}
+
+// Challenge returns the TXT record of the challenge for `domain`, in its
+// zone, without adding it. Its `Record` is unset.
+func (p *DNSProvider) Challenge(
+	ctx context.Context, domain, keyAuth string,
+) (*Challenge, error) {
+	challenge, _, err := p.challenge(ctx, domain, keyAuth)
+	return challenge, err
+}
+
+// PresentContext adds the TXT record of the challenge for `domain`, and
+// returns it. An identical record already in the zone is reused.
+func (p *DNSProvider) PresentContext(
+	ctx context.Context, domain, keyAuth string,
+) (*Challenge, error) {
+	challenge, records, err := p.challenge(ctx, domain, keyAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing := challenge.find(records); len(existing) > 0 {
+		challenge.Record = existing[0]
+		return challenge, nil
+	}
+
+	ttl := p.config.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	saved, err := p.client.AddRecord(ctx, challenge.Zone, gonjalla.Record{
+		Type:    "TXT",
+		Name:    challenge.Name,
+		Content: dnsrecord.FormatTXT(challenge.Value),
+		TTL:     ttl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf(
+			"njalla: adding the challenge record for %s failed: %w",
+			domain, err,
+		)
+	}
+	challenge.Record = saved
+
+	return challenge, nil
+}
+
+// CleanUpContext removes the TXT records of the challenge for `domain`. It
+// isn't an error if there are none.
+func (p *DNSProvider) CleanUpContext(
+	ctx context.Context, domain, keyAuth string,
+) error {
+	challenge, records, err := p.challenge(ctx, domain, keyAuth)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range challenge.find(records) {
+		err := p.client.RemoveRecord(ctx, challenge.Zone, record.ID)
+		if err != nil {
+			return fmt.Errorf(
+				"njalla: removing the challenge record for %s failed: %w",
+				domain, err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// challenge returns the challenge for `domain`, along with the records of
+// its zone.
+func (p *DNSProvider) challenge(
+	ctx context.Context, domain, keyAuth string,
+) (*Challenge, []gonjalla.Record, error) {
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+
+	zone, records, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Challenge{
+		FQDN:  fqdn,
+		Value: value,
+		Zone:  zone,
+		Name:  dnsrecord.NormalizeName(fqdn, zone),
+	}, records, nil
+}
+
+// find returns the TXT records among `records` with the challenge's name and
+// text.
+func (c *Challenge) find(records []gonjalla.Record) []gonjalla.Record {
+	var found []gonjalla.Record
+
+	for _, record := range records {
+		if record.Type != "TXT" ||
+			!strings.EqualFold(dnsrecord.NormalizeName(record.Name, c.Zone), c.Name) {
+			continue
+		}
+
+		if text, err := dnsrecord.ParseTXT(record.Content); err == nil &&
+			text == c.Value {
+			found = append(found, record)
+		}
+	}
+
+	return found
+}
+
+// findZone returns the configured zone and its records or, without one, the
+// longest suffix of `fqdn` whose records can be listed, which is a domain of
+// the account.
+func (p *DNSProvider) findZone(
+	ctx context.Context, fqdn string,
+) (string, []gonjalla.Record, error) {
+	if p.config.Zone != "" {
+		zone := strings.TrimSuffix(dnsrecord.ToASCII(p.config.Zone), ".")
+
+		records, err := p.client.ListRecords(ctx, zone)
+		if err != nil {
+			return "", nil, fmt.Errorf(
+				"njalla: listing the records of %s failed: %w", zone, err,
+			)
+		}
+
+		return zone, records, nil
+	}
+
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	// The challenge label itself is never a domain, and neither is a TLD.
+	for i := 1; i < len(labels)-1; i++ {
+		zone := strings.Join(labels[i:], ".")
+
+		records, err := p.client.ListRecords(ctx, zone)
+		if err == nil {
+			return zone, records, nil
+		}
+
+		// Only an unknown domain means the zone is further up. Any other
+		// error, like a rejected token, would be the same for every zone.
+		var rpcErr *api.RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != api.CodeNotFound {
+			return "", nil, fmt.Errorf(
+				"njalla: listing the records of %s failed: %w", zone, err,
+			)
+		}
+	}
+
+	return "", nil, fmt.Errorf(
+		"njalla: none of the domains of the account contains %s", fqdn,
+	)
+}
@@ -0,0 +1,182 @@
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+func newTestProvider(t *testing.T, config *Config) (*DNSProvider, *njallatest.Server) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	config.Token = "test-token"
+	config.Endpoint = server.URL
+
+	p, err := NewDNSProviderConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p, server
+}
+
+// testChallengeRecords returns the records of the fake at `name`.
+func testChallengeRecords(server *njallatest.Server, name string) []gonjalla.Record {
+	var found []gonjalla.Record
+	for _, record := range server.Records("testing.com") {
+		if record.Name == name {
+			found = append(found, record)
+		}
+	}
+
+	return found
+}
+
+func TestChallengeRecord(t *testing.T) {
+	digest := sha256.Sum256([]byte("token.thumbprint"))
+	expected := base64.RawURLEncoding.EncodeToString(digest[:])
+
+	for domain, name := range map[string]string{
+		"testing.com":        "_acme-challenge.testing.com.",
+		"*.testing.com":      "_acme-challenge.testing.com.",
+		"www.Testing.com.":   "_acme-challenge.www.Testing.com.",
+		"bücher.testing.com": "_acme-challenge.xn--bcher-kva.testing.com.",
+	} {
+		fqdn, value := ChallengeRecord(domain, "token.thumbprint")
+		if fqdn != name || value != expected {
+			t.Errorf(
+				"ChallengeRecord(%q) = %q, %q, expected %q, %q",
+				domain, fqdn, value, name, expected,
+			)
+		}
+	}
+}
+
+func TestPresentCleanUp(t *testing.T) {
+	p, server := newTestProvider(t, &Config{})
+	_, value := ChallengeRecord("www.testing.com", "key-auth")
+
+	if err := p.Present("www.testing.com", "token", "key-auth"); err != nil {
+		t.Fatal(err)
+	}
+	// Presenting the same challenge again doesn't add a duplicate.
+	if err := p.Present("www.testing.com", "token", "key-auth"); err != nil {
+		t.Fatal(err)
+	}
+	// The wildcard's challenge lives at the same name, with its own value.
+	if err := p.Present("*.www.testing.com", "token", "other-auth"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := testChallengeRecords(server, "_acme-challenge.www")
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 challenge records, got %v", records)
+	}
+	for _, record := range records {
+		if record.Type != "TXT" || record.TTL != DefaultTTL {
+			t.Fatalf("Unexpected challenge record %v", record)
+		}
+	}
+
+	if err := p.CleanUp("www.testing.com", "token", "key-auth"); err != nil {
+		t.Fatal(err)
+	}
+
+	records = testChallengeRecords(server, "_acme-challenge.www")
+	if len(records) != 1 || records[0].Content == value {
+		t.Fatalf("Expected only the wildcard's record left, got %v", records)
+	}
+
+	// Cleaning up a challenge without records isn't an error.
+	if err := p.CleanUp("www.testing.com", "token", "key-auth"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPresentContext(t *testing.T) {
+	p, _ := newTestProvider(t, &Config{TTL: 300})
+
+	challenge, err := p.PresentContext(context.Background(), "testing.com", "key-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, value := ChallengeRecord("testing.com", "key-auth")
+	if challenge.Zone != "testing.com" || challenge.Name != "_acme-challenge" ||
+		challenge.Value != value || challenge.Record.ID == "" ||
+		challenge.Record.TTL != 300 {
+		t.Fatalf("Unexpected challenge %+v", challenge)
+	}
+
+	if text, _ := dnsrecord.ParseTXT(challenge.Record.Content); text != value {
+		t.Fatalf("Expected the record to hold %q, got %q", value, text)
+	}
+}
+
+func TestPresentUnknownDomain(t *testing.T) {
+	p, _ := newTestProvider(t, &Config{})
+
+	err := p.Present("www.unknown.com", "token", "key-auth")
+	if err == nil || !strings.Contains(err.Error(), "none of the domains") {
+		t.Fatalf("Expected no domain found, got %v", err)
+	}
+}
+
+func TestPresentRejectedToken(t *testing.T) {
+	p, _ := newTestProvider(t, &Config{})
+	p.client.Token = "wrong-token"
+
+	err := p.Present("www.testing.com", "token", "key-auth")
+	if err == nil || !strings.Contains(err.Error(), "listing the records of www.testing.com failed") {
+		t.Fatalf("Expected the rejected token reported, got %v", err)
+	}
+}
+
+func TestPresentZone(t *testing.T) {
+	p, server := newTestProvider(t, &Config{Zone: "testing.com."})
+
+	if err := p.Present("a.b.testing.com", "token", "key-auth"); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := testChallengeRecords(server, "_acme-challenge.a.b"); len(records) != 1 {
+		t.Fatalf("Expected the challenge record added, got %v", records)
+	}
+}
+
+func TestNewDefaultConfig(t *testing.T) {
+	t.Setenv(EnvToken, "env-token")
+	t.Setenv(EnvTTL, "300")
+	t.Setenv(EnvPropagationTimeout, "120")
+
+	config, err := NewDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Token != "env-token" || config.TTL != 300 ||
+		config.PropagationTimeout != 2*time.Minute ||
+		config.PollingInterval != DefaultPollingInterval {
+		t.Fatalf("Unexpected configuration %+v", config)
+	}
+
+	t.Setenv(EnvPollingInterval, "soon")
+	if _, err := NewDefaultConfig(); err == nil {
+		t.Fatal("Expected an invalid polling interval to fail")
+	}
+}
+
+func TestNewDNSProviderMissingToken(t *testing.T) {
+	if _, err := NewDNSProviderConfig(&Config{}); err == nil {
+		t.Fatal("Expected a missing token to fail")
+	}
+}
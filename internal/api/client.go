@@ -0,0 +1,409 @@
+// Package api is the Njalla JSON-RPC client shared by the Terraform provider
+// and the other packages in this repository.
+//
+// It mirrors the record functions of gonjalla, and reuses its types, but
+// every call takes a `context.Context` that can cancel the underlying HTTP
+// request, goes through an optional shared rate limiter, and is logged
+// through `tflog` with the API token redacted.
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/time/rate"
+
+	"github.com/Sighery/gonjalla"
+)
+
+// DefaultEndpoint is Njalla's JSON-RPC API endpoint.
+const DefaultEndpoint = "https://njal.la/api/1/"
+
+// CodeNotFound is the code of the `RPCError` Njalla answers with when a
+// domain or record doesn't exist, or isn't in the account.
+const CodeNotFound = 404
+
+// redacted is what the API token gets replaced with in logs and errors.
+const redacted = "[REDACTED]"
+
+// Client makes calls to the Njalla API. The zero value is not usable, create
+// clients with `NewClient`.
+type Client struct {
+	Token string
+	// Endpoint is the URL every JSON-RPC request is sent to.
+	Endpoint string
+	// HTTPClient sends the requests. Cancelling the context given to any
+	// call aborts its HTTP request.
+	HTTPClient *http.Client
+	// Limiter is shared by every call made through this client. A nil
+	// limiter means requests are not rate limited.
+	Limiter *rate.Limiter
+}
+
+// NewClient returns a client for the real Njalla API with the given token.
+func NewClient(token string) *Client {
+	return &Client{
+		Token:      token,
+		Endpoint:   DefaultEndpoint,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Error is returned by every failed call made through a `Client`. It carries
+// the request ID logged alongside the call, so a failure reported in a
+// diagnostic can be matched against the `TF_LOG=DEBUG` output.
+type Error struct {
+	Method    string
+	Domain    string
+	RecordID  string
+	RequestID string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	target := fmt.Sprintf("domain %s", e.Domain)
+	if e.RecordID != "" {
+		target = fmt.Sprintf("record %s in domain %s", e.RecordID, e.Domain)
+	}
+
+	return fmt.Sprintf(
+		"Njalla API call %s for %s failed (request ID %s): %s",
+		e.Method, target, e.RequestID, e.Err,
+	)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RPCError is an error reported by the Njalla API in a JSON-RPC response.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// HTTPError is returned when the Njalla API answers with a non-200 status.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf(
+		"unexpected HTTP status %d %s: %s",
+		e.StatusCode, http.StatusText(e.StatusCode), e.Body,
+	)
+}
+
+// ListRecords returns all the records for a given domain.
+func (c *Client) ListRecords(
+	ctx context.Context, domain string,
+) ([]gonjalla.Record, error) {
+	params := map[string]interface{}{
+		"domain": domain,
+	}
+
+	var response struct {
+		Records []gonjalla.Record `json:"records"`
+	}
+
+	err := c.call(ctx, "list-records", domain, "", params, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Records, nil
+}
+
+// AddRecord adds a record to a given domain, returning the record as saved
+// by Njalla, with its ID filled in.
+func (c *Client) AddRecord(
+	ctx context.Context, domain string, record gonjalla.Record,
+) (gonjalla.Record, error) {
+	params, err := recordParams(domain, record)
+	if err != nil {
+		return gonjalla.Record{}, err
+	}
+
+	var saved gonjalla.Record
+
+	err = c.call(ctx, "add-record", domain, "", params, &saved)
+	if err != nil {
+		return gonjalla.Record{}, err
+	}
+
+	return saved, nil
+}
+
+// EditRecord replaces a record of a given domain with all the filled fields
+// of `record`, which must have its ID set.
+func (c *Client) EditRecord(
+	ctx context.Context, domain string, record gonjalla.Record,
+) error {
+	params, err := recordParams(domain, record)
+	if err != nil {
+		return err
+	}
+
+	return c.call(ctx, "edit-record", domain, record.ID, params, nil)
+}
+
+// RemoveRecord removes a record from a given domain.
+func (c *Client) RemoveRecord(
+	ctx context.Context, domain string, id string,
+) error {
+	params := map[string]interface{}{
+		"domain": domain,
+		"id":     id,
+	}
+
+	return c.call(ctx, "remove-record", domain, id, params, nil)
+}
+
+// recordParams flattens a record into the params of a JSON-RPC request, the
+// same way gonjalla does.
+func recordParams(
+	domain string, record gonjalla.Record,
+) (map[string]interface{}, error) {
+	marshal, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(marshal, &params); err != nil {
+		return nil, err
+	}
+	params["domain"] = domain
+
+	return params, nil
+}
+
+// call runs a single Njalla API call, logging the method, domain, record ID,
+// latency and HTTP status through `tflog`. If the client has a rate limiter,
+// the call first waits for its turn. The API token is masked from every log
+// line, and any error returned is an `*Error` with the token scrubbed from
+// its message.
+func (c *Client) call(
+	ctx context.Context,
+	method string,
+	domain string,
+	recordID string,
+	params map[string]interface{},
+	result interface{},
+) error {
+	requestID := newRequestID()
+
+	ctx = c.maskedContext(ctx)
+	ctx = tflog.SetField(ctx, "njalla_method", method)
+	ctx = tflog.SetField(ctx, "njalla_domain", domain)
+	ctx = tflog.SetField(ctx, "njalla_request_id", requestID)
+	if recordID != "" {
+		ctx = tflog.SetField(ctx, "njalla_record_id", recordID)
+	}
+
+	wrap := func(err error) error {
+		return &Error{
+			Method:    method,
+			Domain:    domain,
+			RecordID:  recordID,
+			RequestID: requestID,
+			Err:       c.redactError(err),
+		}
+	}
+
+	fields := map[string]interface{}{}
+
+	if err := ctx.Err(); err != nil {
+		tflog.Error(ctx, "Njalla API request not sent", map[string]interface{}{
+			"status": "context_done",
+			"error":  err.Error(),
+		})
+		return wrap(err)
+	}
+
+	if c.Limiter != nil {
+		waitStart := time.Now()
+		if err := c.Limiter.Wait(ctx); err != nil {
+			tflog.Error(ctx, "Njalla API request not sent", map[string]interface{}{
+				"status": "rate_limit_wait_failed",
+				"error":  err.Error(),
+			})
+			return wrap(err)
+		}
+		fields["rate_limit_wait_ms"] = time.Since(waitStart).Milliseconds()
+	}
+
+	tflog.Debug(ctx, "Sending Njalla API request")
+
+	start := time.Now()
+	httpStatus, err := c.do(ctx, requestID, method, params, result)
+	fields["latency_ms"] = time.Since(start).Milliseconds()
+	if httpStatus != 0 {
+		fields["http_status"] = httpStatus
+	}
+
+	if err != nil {
+		err = wrap(err)
+		fields["status"] = "error"
+		fields["error"] = err.Error()
+		tflog.Error(ctx, "Njalla API request failed", fields)
+
+		return err
+	}
+
+	fields["status"] = "ok"
+	tflog.Debug(ctx, "Received Njalla API response", fields)
+
+	return nil
+}
+
+// do sends a single JSON-RPC request, decoding its result into `result`
+// unless nil. It returns the HTTP status code of the response, or 0 if none
+// was received.
+func (c *Client) do(
+	ctx context.Context,
+	requestID string,
+	method string,
+	params map[string]interface{},
+	result interface{},
+) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body),
+	)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Njalla %s", c.Token))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", requestID)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       truncate(string(data), 200),
+		}
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return resp.StatusCode, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if response.Error != nil {
+		return resp.StatusCode, response.Error
+	}
+	if response.Result == nil {
+		return resp.StatusCode, fmt.Errorf(
+			"missing result in response: %s", truncate(string(data), 200),
+		)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return resp.StatusCode, fmt.Errorf("decoding result: %w", err)
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// maskedContext returns a logging context in which the API token, and any
+// field that could carry it, is always redacted.
+func (c *Client) maskedContext(ctx context.Context) context.Context {
+	ctx = tflog.MaskFieldValuesWithFieldKeys(
+		ctx, "token", "api_token", "Authorization",
+	)
+	if c.Token != "" {
+		ctx = tflog.MaskLogStrings(ctx, c.Token)
+	}
+
+	return ctx
+}
+
+// redactError replaces any occurrence of the API token in an error message.
+// The original error is kept as the cause, so callers can still match it
+// with `errors.Is` and `errors.As`.
+func (c *Client) redactError(err error) error {
+	if c.Token == "" || !strings.Contains(err.Error(), c.Token) {
+		return err
+	}
+
+	return &redactedError{
+		message: strings.ReplaceAll(err.Error(), c.Token, redacted),
+		err:     err,
+	}
+}
+
+// redactedError is an error whose message had the API token scrubbed.
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// newRequestID returns a short random identifier used to correlate the log
+// lines and diagnostics belonging to a single API call.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n] + "..."
+}
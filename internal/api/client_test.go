@@ -0,0 +1,369 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"golang.org/x/time/rate"
+
+	"github.com/Sighery/gonjalla"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("secret-token")
+	client.Endpoint = server.URL
+	return client
+}
+
+func respond(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	})
+}
+
+func TestListRecordsExpected(t *testing.T) {
+	var method string
+	var authorization string
+
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var request struct {
+				Method string `json:"method"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			method = request.Method
+			authorization = r.Header.Get("Authorization")
+
+			w.Write([]byte(`{"jsonrpc": "2.0", "result": {"records": [
+				{"id": "1", "name": "@", "type": "A", "content": "1.1.1.1", "ttl": 10800}
+			]}}`))
+		},
+	))
+
+	records, err := client.ListRecords(context.Background(), "testing.com")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if method != "list-records" {
+		t.Fatalf("Unexpected method %s", method)
+	}
+	if authorization != "Njalla secret-token" {
+		t.Fatalf("Unexpected Authorization header %s", authorization)
+	}
+
+	expected := []gonjalla.Record{
+		{ID: "1", Name: "@", Type: "A", Content: "1.1.1.1", TTL: 10800},
+	}
+	if len(records) != 1 || records[0] != expected[0] {
+		t.Fatalf("Expected %v, got %v", expected, records)
+	}
+}
+
+func TestCallRPCError(t *testing.T) {
+	client := newTestClient(t, respond(
+		`{"jsonrpc": "2.0", "error": {"code": 403, "message": "Permission denied"}}`,
+	))
+
+	err := client.RemoveRecord(context.Background(), "testing.com", "1234")
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Expected *RPCError, got %v", err)
+	}
+	if rpcErr.Code != 403 {
+		t.Fatalf("Unexpected code %d", rpcErr.Code)
+	}
+}
+
+func TestCallHTTPError(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	))
+
+	_, err := client.ListRecords(context.Background(), "testing.com")
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected *HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Unexpected status %d", httpErr.StatusCode)
+	}
+}
+
+func TestCallLogsRequest(t *testing.T) {
+	client := newTestClient(t, respond(
+		`{"jsonrpc": "2.0", "result": {"records": []}}`,
+	))
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	if _, err := client.ListRecords(ctx, "testing.com"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d: %v", len(entries), entries)
+	}
+
+	response := entries[1]
+	if response["njalla_method"] != "list-records" {
+		t.Fatalf("Unexpected method field: %v", response["njalla_method"])
+	}
+	if response["njalla_domain"] != "testing.com" {
+		t.Fatalf("Unexpected domain field: %v", response["njalla_domain"])
+	}
+	if response["status"] != "ok" {
+		t.Fatalf("Unexpected status field: %v", response["status"])
+	}
+	if response["http_status"] != float64(200) {
+		t.Fatalf("Unexpected http_status field: %v", response["http_status"])
+	}
+	if _, ok := response["latency_ms"]; !ok {
+		t.Fatal("Missing latency_ms field")
+	}
+	if response["njalla_request_id"] != entries[0]["njalla_request_id"] {
+		t.Fatal("Request and response entries have different request IDs")
+	}
+}
+
+func TestCallRedactsToken(t *testing.T) {
+	client := newTestClient(t, respond(
+		`{"jsonrpc": "2.0", "error": {"message": "bad token secret-token"}}`,
+	))
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	err := client.RemoveRecord(ctx, "testing.com", "1234")
+	if err == nil {
+		t.Fatal("Unexpected success")
+	}
+
+	if strings.Contains(err.Error(), client.Token) {
+		t.Fatalf("Token leaked into error: %s", err)
+	}
+	if strings.Contains(output.String(), client.Token) {
+		t.Fatalf("Token leaked into logs: %s", output.String())
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *Error, got %T", err)
+	}
+	if apiErr.RequestID == "" {
+		t.Fatal("Missing request ID")
+	}
+	if !strings.Contains(err.Error(), apiErr.RequestID) {
+		t.Fatalf("Request ID missing from error: %s", err)
+	}
+	if apiErr.RecordID != "1234" {
+		t.Fatalf("Unexpected record ID: %s", apiErr.RecordID)
+	}
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Expected the redacted error to wrap *RPCError, got %v", err)
+	}
+}
+
+func TestCallRedactedDeadline(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			<-r.Context().Done()
+		},
+	))
+	// The transport error quotes the URL, which now carries the token.
+	client.Endpoint += "/" + client.Token
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.ListRecords(ctx, "testing.com")
+	if strings.Contains(err.Error(), client.Token) {
+		t.Fatalf("Token leaked into error: %s", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestCallCancelledMidRequest(t *testing.T) {
+	aborted := make(chan struct{})
+
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// The server only notices a client going away once the body
+			// has been consumed.
+			io.ReadAll(r.Body)
+
+			select {
+			case <-r.Context().Done():
+				close(aborted)
+			case <-time.After(5 * time.Second):
+				w.Write([]byte(`{"jsonrpc": "2.0", "result": {"records": []}}`))
+			}
+		},
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.ListRecords(ctx, "testing.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Call returned after %s, cancellation was ignored", elapsed)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("Server never saw the request being aborted")
+	}
+}
+
+func TestCallDeadlineMidRequest(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// The server only notices a client going away once the body
+			// has been consumed.
+			io.ReadAll(r.Body)
+
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.AddRecord(ctx, "testing.com", gonjalla.Record{Type: "A"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestCallNotSentWithDoneContext(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("Unexpected request with a done context")
+		},
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.ListRecords(ctx, "testing.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLimiterRespectedUnderParallelism(t *testing.T) {
+	const (
+		rps      = 50
+		burst    = 5
+		requests = 40
+		window   = 200 * time.Millisecond
+	)
+
+	var mu sync.Mutex
+	var times []time.Time
+
+	client := newTestClient(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+
+			w.Write([]byte(`{"jsonrpc": "2.0", "result": {"records": []}}`))
+		},
+	))
+	client.Limiter = rate.NewLimiter(rps, burst)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ListRecords(context.Background(), "testing.com"); err != nil {
+				t.Errorf("%q", err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if len(times) != requests {
+		t.Fatalf("Expected %d requests, got %d", requests, len(times))
+	}
+
+	minimum := time.Duration(requests-burst) * time.Second / rps
+	if elapsed < minimum {
+		t.Fatalf("%d requests took %s, expected at least %s", requests, elapsed, minimum)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	allowed := int(rps*window.Seconds()) + burst
+	for i := range times {
+		count := 0
+		for j := i; j < len(times) && times[j].Sub(times[i]) < window; j++ {
+			count++
+		}
+		if count > allowed {
+			t.Fatalf(
+				"%d requests within %s, expected at most %d",
+				count, window, allowed,
+			)
+		}
+	}
+}
+
+func TestLimiterWaitHonoursContext(t *testing.T) {
+	client := newTestClient(t, respond(
+		`{"jsonrpc": "2.0", "result": {"records": []}}`,
+	))
+	client.Limiter = rate.NewLimiter(0.001, 1)
+
+	// The first request takes the only token in the bucket, the second would
+	// wait far longer than the context deadline allows.
+	if _, err := client.ListRecords(context.Background(), "testing.com"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.ListRecords(ctx, "testing.com"); err == nil {
+		t.Fatal("Unexpected success")
+	}
+}
@@ -0,0 +1,228 @@
+package dnsrecord
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CAATags are the property tags accepted by the provider: the ones defined
+// in RFC 8659 section 4.2, and `issuemail` from RFC 9495.
+var CAATags = []string{"issue", "issuewild", "iodef", "issuemail"}
+
+var (
+	caaContentRegex = regexp.MustCompile(
+		`^(\d{1,3})\s+([A-Za-z0-9]+)\s+(\S.*?)\s*$`,
+	)
+	caaIssuerRegex = regexp.MustCompile(
+		`^(?:[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?\.)*` +
+			`[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?$`,
+	)
+	caaParameterRegex = regexp.MustCompile(
+		`^([A-Za-z0-9]+)\s*=\s*([\x21-\x3A\x3C-\x7E]*)$`,
+	)
+	caaValidationMethodRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+// CAAContent is the parsed form of a CAA record's content. The value is kept
+// without its surrounding quotes, and the tag in lower case.
+type CAAContent struct {
+	Flags int
+	Tag   string
+	Value string
+}
+
+// String returns the canonical serialization of the content, with the value
+// always quoted.
+func (c CAAContent) String() string {
+	value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(c.Value)
+
+	return fmt.Sprintf("%d %s \"%s\"", c.Flags, c.Tag, value)
+}
+
+// Validate checks the flags and tag, and the value according to the tag.
+func (c CAAContent) Validate() error {
+	if 0 > c.Flags || c.Flags > 255 {
+		return fmt.Errorf("flag must be between 0 and 255: RFC 8659 4.1.1")
+	}
+
+	switch c.Tag {
+	case "issue", "issuewild":
+		return validateCAAIssueValue(c.Tag, c.Value, "RFC 8659 4.2")
+	case "issuemail":
+		return validateCAAIssueValue(c.Tag, c.Value, "RFC 9495 3")
+	case "iodef":
+		return validateCAAIodefValue(c.Value)
+	}
+
+	return fmt.Errorf(
+		"tag must be one of %s, got: %s: RFC 8659 4.2",
+		strings.Join(CAATags, ", "), c.Tag,
+	)
+}
+
+// ParseCAA parses and validates the content of a CAA record:
+// `flags tag value`, where value may be quoted.
+func ParseCAA(v string) (CAAContent, error) {
+	matches := caaContentRegex.FindStringSubmatch(v)
+	if matches == nil {
+		return CAAContent{}, fmt.Errorf(
+			"value must follow RFC 8659: point 4 for syntax",
+		)
+	}
+
+	flags, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return CAAContent{}, fmt.Errorf("flag is not int: RFC 8659 point 4.1.1")
+	}
+
+	value, err := unquoteCAAValue(matches[3])
+	if err != nil {
+		return CAAContent{}, err
+	}
+
+	parsed := CAAContent{
+		Flags: flags,
+		Tag:   strings.ToLower(matches[2]),
+		Value: value,
+	}
+	if err := parsed.Validate(); err != nil {
+		return CAAContent{}, err
+	}
+
+	return parsed, nil
+}
+
+// unquoteCAAValue removes the quotes around a value, if any, along with the
+// escaping of backslashes and quotes inside of it.
+func unquoteCAAValue(v string) (string, error) {
+	if !strings.HasPrefix(v, `"`) {
+		if strings.ContainsAny(v, " \t\"") {
+			return "", fmt.Errorf(
+				"value with spaces or quotes must be quoted, got: %s: "+
+					"RFC 8659 point 4.1.1",
+				v,
+			)
+		}
+
+		return v, nil
+	}
+
+	var value strings.Builder
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			if i+1 == len(v) {
+				break
+			}
+			i++
+			value.WriteByte(v[i])
+		case '"':
+			if i != len(v)-1 {
+				return "", fmt.Errorf(
+					"unexpected characters after quoted value, got: %s: "+
+						"RFC 8659 point 4.1.1",
+					v,
+				)
+			}
+
+			return value.String(), nil
+		default:
+			value.WriteByte(v[i])
+		}
+	}
+
+	return "", fmt.Errorf(
+		"unterminated quoted value, got: %s: RFC 8659 point 4.1.1", v,
+	)
+}
+
+// validateCAAIssueValue checks the value of an `issue`, `issuewild` or
+// `issuemail` property: an optional issuer domain name, followed by `;`
+// separated `key=value` parameters. The `accounturi` parameter must be a URI
+// (RFC 8657 section 3) and `validationmethods` a comma separated list of
+// method names (RFC 8657 section 4).
+func validateCAAIssueValue(tag string, value string, rfc string) error {
+	parts := strings.Split(value, ";")
+
+	issuer := strings.TrimSpace(parts[0])
+	if issuer != "" && !caaIssuerRegex.MatchString(issuer) {
+		return fmt.Errorf(
+			"%s value must start with an issuer domain name, got: %s: %s",
+			tag, issuer, rfc,
+		)
+	}
+
+	if len(parts) == 1 {
+		return nil
+	}
+
+	parameters := parts[1:]
+	if len(parameters) == 1 && strings.TrimSpace(parameters[0]) == "" {
+		return nil
+	}
+
+	for _, parameter := range parameters {
+		matches := caaParameterRegex.FindStringSubmatch(
+			strings.TrimSpace(parameter),
+		)
+		if matches == nil {
+			return fmt.Errorf(
+				"%s value parameters must be key=value pairs separated by ;, "+
+					"got: %s: %s",
+				tag, strings.TrimSpace(parameter), rfc,
+			)
+		}
+
+		key, parameterValue := strings.ToLower(matches[1]), matches[2]
+		switch key {
+		case "accounturi":
+			uri, err := url.Parse(parameterValue)
+			if err != nil || uri.Scheme == "" {
+				return fmt.Errorf(
+					"%s accounturi parameter must be a URI, got: %s: "+
+						"RFC 8657 3",
+					tag, parameterValue,
+				)
+			}
+		case "validationmethods":
+			for _, method := range strings.Split(parameterValue, ",") {
+				if !caaValidationMethodRegex.MatchString(method) {
+					return fmt.Errorf(
+						"%s validationmethods parameter must be a comma "+
+							"separated list of methods, got: %s: RFC 8657 4",
+						tag, parameterValue,
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCAAIodefValue checks the value of an `iodef` property is a
+// `mailto:`, `http:` or `https:` URL.
+func validateCAAIodefValue(value string) error {
+	uri, err := url.Parse(value)
+	if err == nil {
+		switch uri.Scheme {
+		case "mailto":
+			if strings.Contains(uri.Opaque, "@") {
+				return nil
+			}
+		case "http", "https":
+			if uri.Host != "" {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf(
+		"iodef value must be a mailto:, http: or https: URL, got: %s: "+
+			"RFC 8659 4.4",
+		value,
+	)
+}
@@ -0,0 +1,64 @@
+package dnsrecord
+
+import "testing"
+
+func TestParseCAAContent(t *testing.T) {
+	valid := map[string]CAAContent{
+		`0 issue "letsencrypt.org"`: {Tag: "issue", Value: "letsencrypt.org"},
+		`0 ISSUE letsencrypt.org`:   {Tag: "issue", Value: "letsencrypt.org"},
+		`0 issue ";"`:               {Tag: "issue", Value: ";"},
+		`0 issue ""`:                {Tag: "issue"},
+		`128 issuewild "ca.example.net; accounturi=https://ca.example.net/acct/1; validationmethods=dns-01,http-01"`: {
+			Flags: 128,
+			Tag:   "issuewild",
+			Value: "ca.example.net; accounturi=https://ca.example.net/acct/1; " +
+				"validationmethods=dns-01,http-01",
+		},
+		`0 issuemail "ca.example.net"`: {Tag: "issuemail", Value: "ca.example.net"},
+		`0 iodef "mailto:security@example.com"`: {
+			Tag: "iodef", Value: "mailto:security@example.com",
+		},
+		`0 iodef "https://iodef.example.com/"`: {
+			Tag: "iodef", Value: "https://iodef.example.com/",
+		},
+		`0 issue "ca.example.net; key=\"quoted\""`: {
+			Tag: "issue", Value: `ca.example.net; key="quoted"`,
+		},
+	}
+
+	for content, expected := range valid {
+		parsed, err := ParseCAA(content)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", content, err)
+		}
+		if parsed != expected {
+			t.Fatalf("Parsed %q as %+v, expected %+v", content, parsed, expected)
+		}
+		if reparsed, err := ParseCAA(parsed.String()); err != nil ||
+			reparsed != parsed {
+			t.Fatalf("%q didn't round trip: %+v, %v", content, reparsed, err)
+		}
+	}
+
+	invalid := []string{
+		`0 issue`,
+		`256 issue "letsencrypt.org"`,
+		`0 unknown "letsencrypt.org"`,
+		`0 issue "-letsencrypt.org"`,
+		`0 issue "letsencrypt.org; accounturi"`,
+		`0 issue "letsencrypt.org; accounturi=not a uri"`,
+		`0 issue "letsencrypt.org; validationmethods=dns-01,,http-01"`,
+		`0 issue "letsencrypt.org`,
+		`0 issue "letsencrypt.org" extra`,
+		`0 issue lets encrypt`,
+		`0 iodef "letsencrypt.org"`,
+		`0 iodef "mailto:"`,
+		`0 iodef "ftp://example.com"`,
+	}
+
+	for _, content := range invalid {
+		if _, err := ParseCAA(content); err == nil {
+			t.Fatalf("Unexpected success for %q", content)
+		}
+	}
+}
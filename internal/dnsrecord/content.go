@@ -0,0 +1,194 @@
+package dnsrecord
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Types are the record types handled in this repository, each with the
+// checks of `Validate`.
+var Types = []string{
+	"A", "AAAA", "CAA", "CNAME", "MX", "NAPTR", "NS", "PTR", "TLSA", "TXT",
+}
+
+// HasPriority reports whether records of `recordType` have a priority. Of
+// the handled types, only MX records do.
+func HasPriority(recordType string) bool {
+	return recordType == "MX"
+}
+
+// Content returns the content sent to the API for a record of `recordType`
+// with `value`: hostnames in their ASCII form, and the text of TXT records
+// split into character-strings when needed.
+func Content(recordType string, value string) string {
+	switch recordType {
+	case "CNAME", "MX", "NS", "PTR":
+		return ToASCII(value)
+	case "TXT":
+		return FormatTXT(value)
+	}
+
+	return value
+}
+
+// Value returns the value of a record from its content returned by the API,
+// reversing `Content`: TXT records hold their text, unquoted. Hostnames are
+// kept as returned.
+func Value(recordType string, content string) string {
+	if recordType == "TXT" {
+		if text, err := ParseTXT(content); err == nil {
+			return text
+		}
+	}
+
+	return content
+}
+
+// Validate checks `content` is valid for a record of `recordType`, as the
+// Terraform resource of that type checks its `content`. `key` names the
+// content in errors.
+func Validate(key string, recordType string, content string) error {
+	switch recordType {
+	case "A":
+		// IPv4-mapped IPv6 addresses like ::ffff:1.2.3.4 parse as IPv4
+		// addresses too, so only dotted quads are accepted.
+		ip := net.ParseIP(content)
+		if ip == nil || ip.To4() == nil || strings.Contains(content, ":") {
+			return fmt.Errorf(
+				"expected %s to contain a valid IPv4 address, got: %s",
+				key, content,
+			)
+		}
+	case "AAAA":
+		ip := net.ParseIP(content)
+		if ip == nil || ip.To4() != nil || !strings.Contains(content, ":") {
+			return fmt.Errorf(
+				"expected %s to contain a valid IPv6 address, got: %s",
+				key, content,
+			)
+		}
+	case "CNAME":
+		return ValidateHostname(key, content, true)
+	case "MX", "NS", "PTR":
+		return ValidateHostname(key, content, false)
+	case "TXT":
+		text, err := ParseTXT(content)
+		if err != nil {
+			return err
+		}
+		if len(text) > TXTMaxLength {
+			return fmt.Errorf(
+				"expected TXT content to be at most %d bytes, got: %d",
+				TXTMaxLength, len(text),
+			)
+		}
+	case "CAA":
+		_, err := ParseCAA(content)
+		return err
+	case "NAPTR":
+		_, err := ParseNAPTR(content)
+		return err
+	case "TLSA":
+		_, err := ParseTLSA(content)
+		return err
+	default:
+		return fmt.Errorf(
+			"expected a record type among %s, got: %s",
+			strings.Join(Types, ", "), recordType,
+		)
+	}
+
+	return nil
+}
+
+// ParseUnsigned parses a decimal field of a record's content. Unlike
+// `strconv.Atoi` it rejects signs, so `+1` or `-0` are not accepted.
+func ParseUnsigned(s string) (int, error) {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%q is not an unsigned int", s)
+		}
+	}
+
+	return strconv.Atoi(s)
+}
+
+// IsDomainName reports whether s is a syntactically valid domain name, with
+// or without a trailing dot. Labels may contain underscores, as used by
+// service names like `_sip._udp.example.com`.
+func IsDomainName(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			switch {
+			case 'a' <= c && c <= 'z':
+			case 'A' <= c && c <= 'Z':
+			case '0' <= c && c <= '9':
+			case c == '-' || c == '_':
+			default:
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ValidateHostname checks `name` is a hostname as defined by RFC 1123, with
+// internationalized labels allowed in either their Unicode or ASCII form.
+// Underscores are only allowed if `allowUnderscore` is set. IP addresses and
+// URLs, common mistakes, are rejected with a specific error. `key` names the
+// hostname in errors.
+func ValidateHostname(key string, name string, allowUnderscore bool) error {
+	trimmed := strings.TrimSuffix(name, ".")
+
+	if net.ParseIP(trimmed) != nil {
+		return fmt.Errorf(
+			"expected %s to be a hostname, got the IP address %s. Use an A "+
+				"or AAAA record to point a name to an address",
+			key, name,
+		)
+	}
+
+	if strings.Contains(trimmed, "://") || strings.Contains(trimmed, "/") {
+		return fmt.Errorf(
+			"expected %s to be a hostname, got what looks like a URL: %s. "+
+				"Check RFC 1123 section 2.1",
+			key, name,
+		)
+	}
+
+	ascii, err := Profile.ToASCII(trimmed)
+	if err != nil || !IsDomainName(ascii) ||
+		(!allowUnderscore && strings.Contains(ascii, "_")) {
+		return fmt.Errorf(
+			"expected %s to be a valid hostname, got: %s. Check RFC 1123 "+
+				"section 2.1",
+			key, name,
+		)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if _, err := ParseUnsigned(labels[len(labels)-1]); err == nil {
+		return fmt.Errorf(
+			"expected %s to be a valid hostname, got: %s. The last label "+
+				"can't be all numeric",
+			key, name,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package dnsrecord
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnsigned(t *testing.T) {
+	for input, expected := range map[string]int{"0": 0, "255": 255, "007": 7} {
+		result, err := ParseUnsigned(input)
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+		if result != expected {
+			t.Fatalf("Expected %d for %q, got %d", expected, input, result)
+		}
+	}
+}
+
+func TestParseUnsignedInvalid(t *testing.T) {
+	for _, input := range []string{"", "+1", "-0", "1a", " 1"} {
+		if _, err := ParseUnsigned(input); err == nil {
+			t.Fatalf("Unexpected success for %q", input)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := map[string][]string{
+		"A":     {"1.2.3.4"},
+		"AAAA":  {"2001:db8::1"},
+		"CAA":   {`0 issue "letsencrypt.org"`},
+		"CNAME": {"s1._domainkey.example.com."},
+		"MX":    {"mail.example.com", "bücher.example"},
+		"NAPTR": {`100 10 "" "" "/urn:cid:.+@([^\.]+\.)(.*)$/\2/i" .`},
+		"NS":    {"ns1.example.com."},
+		"PTR":   {"host.example.com."},
+		"TLSA":  {"3 1 0 abcd"},
+		"TXT":   {"v=spf1 -all", `"v=DKIM1; " "p=abcd"`},
+	}
+
+	for recordType, contents := range valid {
+		for _, content := range contents {
+			if err := Validate("value", recordType, content); err != nil {
+				t.Errorf("Unexpected error for %s %q: %s", recordType, content, err)
+			}
+		}
+	}
+
+	invalid := map[string][]string{
+		"A":     {"2001:db8::1", "example.com"},
+		"AAAA":  {"example.com"},
+		"CAA":   {`0 unknown "letsencrypt.org"`},
+		"CNAME": {"https://example.com"},
+		"MX":    {"1.2.3.4", "_mail.example.com", "example.123"},
+		"NAPTR": {`100 10 "X" "" "" .`},
+		"NS":    {"ns1..example.com"},
+		"PTR":   {""},
+		"TLSA":  {"3 1 1 abcd"},
+		"TXT":   {strings.Repeat("a", TXTMaxLength+1)},
+		"SRV":   {"10 5 5060 sip.example.com."},
+	}
+
+	for recordType, contents := range invalid {
+		for _, content := range contents {
+			if err := Validate("value", recordType, content); err == nil {
+				t.Errorf("Unexpected success for %s %q", recordType, content)
+			}
+		}
+	}
+}
+
+func TestValidateAddressFamilies(t *testing.T) {
+	cases := []struct {
+		content string
+		a       bool
+		aaaa    bool
+	}{
+		{"1.2.3.4", true, false},
+		{"2001:db8::1", false, true},
+		{"::1", false, true},
+		{"::ffff:1.2.3.4", false, false},
+		{"::ffff:0102:0304", false, false},
+		{"::1.2.3.4", false, true},
+		{"1.2.3", false, false},
+		{"1.2.3.4 ", false, false},
+		{"", false, false},
+	}
+
+	for _, c := range cases {
+		if err := Validate("value", "A", c.content); (err == nil) != c.a {
+			t.Errorf("Expected A %q to be valid: %t, got: %v", c.content, c.a, err)
+		}
+		if err := Validate("value", "AAAA", c.content); (err == nil) != c.aaaa {
+			t.Errorf("Expected AAAA %q to be valid: %t, got: %v", c.content, c.aaaa, err)
+		}
+	}
+}
+
+func TestValidateHostnameKey(t *testing.T) {
+	err := ValidateHostname("values.value", "1.2.3.4", false)
+	if err == nil || !strings.HasPrefix(err.Error(), "expected values.value ") {
+		t.Fatalf("Expected an error naming the key, got %v", err)
+	}
+}
+
+func TestContentValue(t *testing.T) {
+	long := strings.Repeat("a", TXTMaxStringLength+1)
+
+	cases := []struct {
+		recordType string
+		value      string
+		content    string
+	}{
+		{"A", "1.2.3.4", "1.2.3.4"},
+		{"MX", "mail.bücher.example.", "mail.xn--bcher-kva.example."},
+		{"TXT", "v=spf1 -all", "v=spf1 -all"},
+		{"TXT", long, FormatTXT(long)},
+		{"CAA", `0 issue "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+	}
+
+	for _, c := range cases {
+		if content := Content(c.recordType, c.value); content != c.content {
+			t.Errorf(
+				"Content(%s, %q) = %q, expected %q",
+				c.recordType, c.value, content, c.content,
+			)
+		}
+	}
+
+	if value := Value("TXT", FormatTXT(long)); value != long {
+		t.Errorf("Expected the TXT text back, got %q", value)
+	}
+	if value := Value("MX", "mail.xn--bcher-kva.example."); value != "mail.xn--bcher-kva.example." {
+		t.Errorf("Expected the hostname as returned, got %q", value)
+	}
+}
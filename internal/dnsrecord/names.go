@@ -0,0 +1,94 @@
+// Package dnsrecord holds the record handling shared by the Terraform
+// provider and the other packages in this repository: the canonical form of
+// record names, the validation of record contents, the encoding of TXT
+// contents sent to the Njalla API, and the TTLs Njalla accepts.
+package dnsrecord
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Profile converts names to their ASCII form, checking internationalized
+// labels follow IDNA2008. Underscores and wildcards are allowed, as record
+// names and some targets like CNAMEs may use them; callers check the ASCII
+// form for the characters they allow.
+var Profile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+)
+
+// ToASCII returns the ASCII form of a name with internationalized labels,
+// keeping any trailing dot. ASCII names, and names that can't be converted,
+// are returned as is; validation reports the latter.
+func ToASCII(name string) string {
+	if IsASCII(name) {
+		return name
+	}
+
+	trimmed := strings.TrimSuffix(name, ".")
+	ascii, err := Profile.ToASCII(trimmed)
+	if err != nil {
+		return name
+	}
+
+	return ascii + name[len(trimmed):]
+}
+
+// EqualNames reports whether two names are the same, ignoring a trailing
+// dot, case, and whether internationalized labels are in their Unicode or
+// ASCII form.
+func EqualNames(a string, b string) bool {
+	return strings.EqualFold(
+		strings.TrimSuffix(ToASCII(a), "."),
+		strings.TrimSuffix(ToASCII(b), "."),
+	)
+}
+
+// IsASCII reports whether s only contains ASCII characters.
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NormalizeName returns the canonical form of a record name: relative to the
+// domain, in ASCII form, without a trailing dot, and `@` for the apex. A name
+// ending in the domain is taken as fully qualified, even without a trailing
+// dot.
+func NormalizeName(name string, domain string) string {
+	name = strings.TrimSuffix(ToASCII(strings.TrimSpace(name)), ".")
+	domain = strings.TrimSuffix(ToASCII(domain), ".")
+
+	if name == "" || name == "@" || strings.EqualFold(name, domain) {
+		return "@"
+	}
+
+	suffix := "." + domain
+	if domain != "" && len(name) > len(suffix) &&
+		strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+		return name[:len(name)-len(suffix)]
+	}
+
+	return name
+}
+
+// FQDN returns the fully qualified name, in ASCII and with a trailing dot, of
+// a record named `name` in `domain`.
+func FQDN(name string, domain string) string {
+	fqdn := strings.TrimSuffix(ToASCII(domain), ".") + "."
+
+	name = NormalizeName(name, domain)
+	if name == "@" {
+		return fqdn
+	}
+
+	return name + "." + fqdn
+}
@@ -0,0 +1,82 @@
+package dnsrecord
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	cases := map[string]string{
+		"@":                     "@",
+		"":                      "@",
+		"example.com":           "@",
+		"example.com.":          "@",
+		"Example.COM.":          "@",
+		"www":                   "www",
+		"www.":                  "www",
+		"www.example.com":       "www",
+		"www.example.com.":      "www",
+		"a.b.Example.com.":      "a.b",
+		"*.example.com":         "*",
+		"_dmarc.example.com.":   "_dmarc",
+		"www.example.org.":      "www.example.org",
+		"notexample.com":        "notexample.com",
+		"www.notexample.com":    "www.notexample.com",
+		"www.example.com.other": "www.example.com.other",
+		"bücher":                "xn--bcher-kva",
+		"bücher.example.com.":   "xn--bcher-kva",
+	}
+
+	for name, expected := range cases {
+		result := NormalizeName(name, "example.com")
+		if result != expected {
+			t.Fatalf(
+				"Normalized %q to %q, expected %q", name, result, expected,
+			)
+		}
+	}
+}
+
+func TestNormalizeNameInternationalizedDomain(t *testing.T) {
+	for _, domain := range []string{"bücher.example", "xn--bcher-kva.example"} {
+		cases := map[string]string{
+			"bücher.example.":            "@",
+			"xn--bcher-kva.example":      "@",
+			"www.bücher.example":         "www",
+			"www.xn--bcher-kva.example.": "www",
+		}
+
+		for name, expected := range cases {
+			result := NormalizeName(name, domain)
+			if result != expected {
+				t.Fatalf(
+					"Normalized %q in %s to %q, expected %q",
+					name, domain, result, expected,
+				)
+			}
+		}
+	}
+}
+
+func TestEqualNames(t *testing.T) {
+	if !EqualNames("Bücher.example.", "xn--bcher-kva.example") {
+		t.Fatal("Expected Unicode and ASCII forms to be equal")
+	}
+
+	if EqualNames("bücher.example", "bucher.example") {
+		t.Fatal("Expected different names to not be equal")
+	}
+}
+
+func TestFQDN(t *testing.T) {
+	cases := map[string]string{
+		"@":                   "testing.com.",
+		"www":                 "www.testing.com.",
+		"www.testing.com.":    "www.testing.com.",
+		"bücher":              "xn--bcher-kva.testing.com.",
+		"*.prod.testing.com.": "*.prod.testing.com.",
+	}
+
+	for name, expected := range cases {
+		if fqdn := FQDN(name, "testing.com"); fqdn != expected {
+			t.Errorf("FQDN(%q) = %q, expected %q", name, fqdn, expected)
+		}
+	}
+}
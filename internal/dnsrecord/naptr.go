@@ -0,0 +1,375 @@
+package dnsrecord
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// naptrRFC is appended to every NAPTR validation error.
+const naptrRFC = "Check RFC 3403 section 4.1"
+
+// NAPTRContent is the parsed form of a NAPTR record's content. The quoted
+// fields are kept without their surrounding quotes.
+type NAPTRContent struct {
+	Order       int
+	Preference  int
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+// String returns the content in the presentation format sent to the API.
+func (n NAPTRContent) String() string {
+	return fmt.Sprintf(
+		"%d %d \"%s\" \"%s\" \"%s\" %s",
+		n.Order, n.Preference, n.Flags, n.Service, n.Regexp, n.Replacement,
+	)
+}
+
+// Validate checks each field, and the constraints between fields.
+func (n NAPTRContent) Validate() error {
+	if n.Order < 0 || n.Order > 65535 {
+		return fmt.Errorf(
+			"expected Order field to be between 0 and 65535 (inclusive), "+
+				"got: %d. %s",
+			n.Order, naptrRFC,
+		)
+	}
+	if n.Preference < 0 || n.Preference > 65535 {
+		return fmt.Errorf(
+			"expected Preference field to be between 0 and 65535 "+
+				"(inclusive), got: %d. %s",
+			n.Preference, naptrRFC,
+		)
+	}
+
+	for _, validate := range []func() error{
+		func() error { return ValidateNAPTRFlags(n.Flags) },
+		func() error { return ValidateNAPTRService(n.Service) },
+		func() error { return ValidateNAPTRRegexp(n.Regexp) },
+		func() error { return ValidateNAPTRReplacement(n.Replacement) },
+	} {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+
+	if n.Regexp != "" && n.Replacement != "." {
+		return fmt.Errorf(
+			"Regexp and Replacement fields are mutually exclusive, "+
+				"Replacement must be \".\" when Regexp is set. %s",
+			naptrRFC,
+		)
+	}
+
+	if strings.ContainsAny(n.Flags, "Uu") && n.Regexp == "" {
+		return fmt.Errorf(
+			"the U flag requires a Regexp producing the URI. " +
+				"Check RFC 3404 section 4.3",
+		)
+	}
+
+	return nil
+}
+
+// ParseNAPTR parses and validates the content of a NAPTR record:
+// `order preference "flags" "service" "regexp" replacement`.
+func ParseNAPTR(v string) (NAPTRContent, error) {
+	fields, err := splitNAPTRFields(v)
+	if err != nil {
+		return NAPTRContent{}, err
+	}
+
+	if len(fields) != 6 {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected 6 arguments, got: %d. %s", len(fields), naptrRFC,
+		)
+	}
+
+	var n NAPTRContent
+
+	if fields[0].quoted {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected Order field to be int, got: %q. %s",
+			fields[0].value, naptrRFC,
+		)
+	}
+	n.Order, err = ParseUnsigned(fields[0].value)
+	if err != nil {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected Order field to be int, got: %s. %s",
+			fields[0].value, naptrRFC,
+		)
+	}
+
+	if fields[1].quoted {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected Preference field to be int, got: %q. %s",
+			fields[1].value, naptrRFC,
+		)
+	}
+	n.Preference, err = ParseUnsigned(fields[1].value)
+	if err != nil {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected Preference field to be int, got: %s. %s",
+			fields[1].value, naptrRFC,
+		)
+	}
+
+	for i, name := range []string{"Flags", "Service", "Regexp"} {
+		if !fields[i+2].quoted {
+			return NAPTRContent{}, fmt.Errorf(
+				"expected %s field to be a quoted string, got: %s. %s",
+				name, fields[i+2].value, naptrRFC,
+			)
+		}
+	}
+	n.Flags = fields[2].value
+	n.Service = fields[3].value
+	n.Regexp = fields[4].value
+
+	if fields[5].quoted {
+		return NAPTRContent{}, fmt.Errorf(
+			"expected Replacement field to be a domain name, got: %q. %s",
+			fields[5].value, naptrRFC,
+		)
+	}
+	n.Replacement = fields[5].value
+
+	if err := n.Validate(); err != nil {
+		return NAPTRContent{}, err
+	}
+
+	return n, nil
+}
+
+type naptrField struct {
+	value  string
+	quoted bool
+}
+
+// splitNAPTRFields splits content on spaces, keeping quoted strings, which
+// may contain spaces, as a single field. Backslash escapes inside quoted
+// strings are kept verbatim, so `\"` doesn't end the string and regexps keep
+// their backreferences.
+func splitNAPTRFields(v string) ([]naptrField, error) {
+	var fields []naptrField
+
+	i := 0
+	for i < len(v) {
+		switch v[i] {
+		case ' ', '\t':
+			i++
+			continue
+		case '"':
+			end := i + 1
+			for end < len(v) && v[end] != '"' {
+				if v[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(v) {
+				return nil, fmt.Errorf(
+					"unterminated quoted string in: %s. %s", v, naptrRFC,
+				)
+			}
+			if end+1 < len(v) && v[end+1] != ' ' && v[end+1] != '\t' {
+				return nil, fmt.Errorf(
+					"expected a space after quoted string %s. %s",
+					v[i:end+1], naptrRFC,
+				)
+			}
+
+			fields = append(fields, naptrField{value: v[i+1 : end], quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(v) && v[end] != ' ' && v[end] != '\t' {
+				end++
+			}
+
+			fields = append(fields, naptrField{value: v[i:end]})
+			i = end
+		}
+	}
+
+	return fields, nil
+}
+
+// ValidateNAPTRFlags checks the flags defined by RFC 3404 section 4.3: any of
+// `S`, `A`, `U` and `P`, case insensitive, each at most once. `S`, `A` and
+// `U` are terminal flags and can't be combined.
+func ValidateNAPTRFlags(flags string) error {
+	seen := map[rune]bool{}
+	terminal := 0
+
+	for _, c := range strings.ToUpper(flags) {
+		switch c {
+		case 'S', 'A', 'U':
+			terminal++
+		case 'P':
+		default:
+			return fmt.Errorf(
+				"expected Flags field to only contain A, S, U or P, got: %s. "+
+					"Check RFC 3404 section 4.3",
+				flags,
+			)
+		}
+
+		if seen[c] {
+			return fmt.Errorf(
+				"expected Flags field to not repeat flags, got: %s. %s",
+				flags, naptrRFC,
+			)
+		}
+		seen[c] = true
+	}
+
+	if terminal > 1 {
+		return fmt.Errorf(
+			"expected Flags field to contain only one of S, A or U, got: %s. "+
+				"Check RFC 3404 section 4.3",
+			flags,
+		)
+	}
+
+	return nil
+}
+
+// naptrServiceRegex follows RFC 3403 section 4.1: an optional protocol
+// followed by `+` separated resolution services. Colons are allowed in
+// services for ENUM services like `E2U+pstn:tel`.
+var naptrServiceRegex = regexp.MustCompile(
+	`^(?:[A-Za-z][A-Za-z0-9\-.]{0,31})?(?:\+[A-Za-z][A-Za-z0-9\-.:]{0,31})*$`,
+)
+
+// ValidateNAPTRService checks the (unquoted) Service field.
+func ValidateNAPTRService(service string) error {
+	if !naptrServiceRegex.MatchString(service) {
+		return fmt.Errorf(
+			"expected Service field to be a protocol followed by "+
+				"+ separated services, got: %s. %s",
+			service, naptrRFC,
+		)
+	}
+
+	return nil
+}
+
+// ValidateNAPTRRegexp checks the (unquoted) Regexp field, a substitution
+// expression as defined in RFC 3402 section 3.2:
+// `delim ere delim replacement delim flags`. The ERE must compile, the only
+// allowed flag is `i`, and backreferences in the replacement must refer to
+// existing groups of the ERE.
+func ValidateNAPTRRegexp(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	rfc := "Check RFC 3402 section 3.2"
+
+	delim, size := utf8.DecodeRuneInString(value)
+	if delim == '\\' || delim == 'i' || unicode.IsDigit(delim) {
+		return fmt.Errorf(
+			"expected Regexp field to start with a delimiter other than a "+
+				"digit, i or \\, got: %s. %s",
+			value, rfc,
+		)
+	}
+
+	parts := splitUnescaped(value[size:], delim)
+	if len(parts) != 3 {
+		return fmt.Errorf(
+			"expected Regexp field to have the form "+
+				"%[1]cregexp%[1]creplacement%[1]cflags, got: %[2]s. %[3]s",
+			delim, value, rfc,
+		)
+	}
+
+	ere, replacement, flags := parts[0], parts[1], parts[2]
+	if flags != "" && flags != "i" {
+		return fmt.Errorf(
+			"expected Regexp field flags to be empty or i, got: %s. %s",
+			flags, rfc,
+		)
+	}
+
+	escapedDelim := `\` + string(delim)
+	if ere == "" {
+		return fmt.Errorf("expected Regexp field to have a regexp. %s", rfc)
+	}
+	compiled, err := regexp.CompilePOSIX(
+		strings.ReplaceAll(ere, escapedDelim, string(delim)),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"expected Regexp field to have a valid POSIX extended regexp, "+
+				"got: %s (%s). %s",
+			ere, err, rfc,
+		)
+	}
+
+	groups := compiled.NumSubexp()
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '\\' || i+1 >= len(replacement) {
+			continue
+		}
+
+		next := replacement[i+1]
+		i++
+		if next < '1' || next > '9' {
+			continue
+		}
+		if int(next-'0') > groups {
+			return fmt.Errorf(
+				"expected Regexp field backreference \\%c to refer to one of "+
+					"the %d groups of the regexp. %s",
+				next, groups, rfc,
+			)
+		}
+	}
+
+	return nil
+}
+
+// splitUnescaped splits s on every occurrence of sep not preceded by a
+// backslash.
+func splitUnescaped(s string, sep rune) []string {
+	var parts []string
+	var current strings.Builder
+
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == sep:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(c)
+	}
+
+	return append(parts, current.String())
+}
+
+// ValidateNAPTRReplacement checks the Replacement field is either `.` or a
+// domain name.
+func ValidateNAPTRReplacement(replacement string) error {
+	if replacement == "." || IsDomainName(replacement) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"expected Replacement field to be . or a domain name, got: %s. %s",
+		replacement, naptrRFC,
+	)
+}
@@ -0,0 +1,63 @@
+package dnsrecord
+
+import "testing"
+
+func TestParseNAPTRContent(t *testing.T) {
+	valid := map[string]NAPTRContent{
+		`100 10 "" "" "/urn:cid:.+@([^\.]+\.)(.*)$/\2/i" .`: {
+			Order: 100, Preference: 10,
+			Regexp: `/urn:cid:.+@([^\.]+\.)(.*)$/\2/i`, Replacement: ".",
+		},
+		`100 50 "s" "http+I2L+I2C+I2R" "" _http._tcp.gatech.edu.`: {
+			Order: 100, Preference: 50, Flags: "s",
+			Service: "http+I2L+I2C+I2R", Replacement: "_http._tcp.gatech.edu.",
+		},
+		`10 0 "u" "E2U+pstn:tel" "!^(.*)$!tel:\1!" .`: {
+			Order: 10, Flags: "u", Service: "E2U+pstn:tel",
+			Regexp: `!^(.*)$!tel:\1!`, Replacement: ".",
+		},
+		`0 0 "P" "" "#a b#c\#d#" .`: {
+			Flags: "P", Regexp: `#a b#c\#d#`, Replacement: ".",
+		},
+	}
+
+	for content, expected := range valid {
+		parsed, err := ParseNAPTR(content)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", content, err)
+		}
+		if parsed != expected {
+			t.Fatalf("Parsed %q as %+v, expected %+v", content, parsed, expected)
+		}
+		if reparsed, err := ParseNAPTR(parsed.String()); err != nil ||
+			reparsed != parsed {
+			t.Fatalf("%q didn't round trip: %+v, %v", content, reparsed, err)
+		}
+	}
+
+	invalid := []string{
+		`100 10 "" "" "" . extra`,
+		`100 10 S "" "" .`,
+		`100 10 "" http "" .`,
+		`100 10 "" "" /a/b/ .`,
+		`100 10 "" "" "" "."`,
+		`100 10 "X" "" "" .`,
+		`100 10 "SS" "" "" .`,
+		`100 10 "U" "E2U+sip" "" .`,
+		`100 10 "" "1http" "" .`,
+		`100 10 "" "" "1a1b1" .`,
+		`100 10 "" "" "/a/b" .`,
+		`100 10 "" "" "/a/b/g" .`,
+		`100 10 "" "" "/(a/b/" .`,
+		`100 10 "" "" "/a//" example.com.`,
+		`100 10 "" "" "" -example.com.`,
+		`100 10 "" "" "unterminated .`,
+		`100 10 """" "" "" .`,
+	}
+
+	for _, content := range invalid {
+		if _, err := ParseNAPTR(content); err == nil {
+			t.Fatalf("Unexpected success for %q", content)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package dnsrecord
+
+import (
+	"net"
+	"strings"
+
+	"github.com/Sighery/gonjalla"
+)
+
+// SameRecord reports whether an existing record has the same type, name and
+// content as a record about to be added, and the same priority if it has
+// one. The TTL isn't compared.
+func SameRecord(existing gonjalla.Record, record gonjalla.Record, domain string) bool {
+	if !strings.EqualFold(existing.Type, record.Type) {
+		return false
+	}
+
+	if !strings.EqualFold(
+		NormalizeName(existing.Name, domain),
+		NormalizeName(record.Name, domain),
+	) {
+		return false
+	}
+
+	if (existing.Priority == nil) != (record.Priority == nil) {
+		return false
+	}
+	if record.Priority != nil && *existing.Priority != *record.Priority {
+		return false
+	}
+
+	return EqualContent(record.Type, existing.Content, record.Content)
+}
+
+// EqualContent reports whether two contents of a record type are
+// equivalent: the same addresses, hostnames or TXT text, and the same CAA
+// property whatever its quoting. These are the comparisons of the Terraform
+// resources' diffs.
+func EqualContent(recordType string, a string, b string) bool {
+	switch strings.ToUpper(recordType) {
+	case "A", "AAAA":
+		ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+		return ipA != nil && ipA.Equal(ipB)
+	case "CNAME", "MX", "NS", "PTR":
+		return EqualNames(a, b)
+	case "TXT":
+		if a == b {
+			return true
+		}
+
+		textA, errA := ParseTXT(a)
+		textB, errB := ParseTXT(b)
+		return errA == nil && errB == nil && textA == textB
+	case "CAA":
+		if a == b {
+			return true
+		}
+
+		caaA, errA := ParseCAA(a)
+		caaB, errB := ParseCAA(b)
+		return errA == nil && errB == nil && caaA == caaB
+	}
+
+	return a == b
+}
@@ -0,0 +1,96 @@
+package dnsrecord
+
+import (
+	"testing"
+
+	"github.com/Sighery/gonjalla"
+)
+
+func TestSameRecord(t *testing.T) {
+	priority := 10
+	otherPriority := 20
+
+	record := gonjalla.Record{
+		Type:     "MX",
+		Name:     "mail",
+		Content:  "mx.example.com",
+		TTL:      3600,
+		Priority: &priority,
+	}
+
+	cases := []struct {
+		existing gonjalla.Record
+		expected bool
+	}{
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail", Content: "mx.example.com",
+				TTL: 3600, Priority: &priority,
+			},
+			true,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail.example.com.", Content: "MX.example.com.",
+				TTL: 10800, Priority: &priority,
+			},
+			true,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail", Content: "mx.example.com",
+				TTL: 3600, Priority: &otherPriority,
+			},
+			false,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "www", Content: "mx.example.com",
+				TTL: 3600, Priority: &priority,
+			},
+			false,
+		},
+		{
+			gonjalla.Record{
+				Type: "CNAME", Name: "mail", Content: "mx.example.com",
+				TTL: 3600,
+			},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		if same := SameRecord(c.existing, record, "example.com"); same != c.expected {
+			t.Errorf(
+				"SameRecord(%+v) = %t, expected %t", c.existing, same, c.expected,
+			)
+		}
+	}
+}
+
+func TestEqualContent(t *testing.T) {
+	cases := []struct {
+		recordType string
+		a          string
+		b          string
+		expected   bool
+	}{
+		{"A", "1.1.1.1", "1.1.1.1", true},
+		{"A", "1.1.1.1", "1.1.1.2", false},
+		{"AAAA", "2001:db8::1", "2001:0db8:0:0:0:0:0:1", true},
+		{"CNAME", "example.com", "EXAMPLE.com.", true},
+		{"TXT", "text", "\"text\"", true},
+		{"TXT", "text", "other", false},
+		{"CAA", "0 issue \"letsencrypt.org\"", "0 ISSUE letsencrypt.org", true},
+		{"TLSA", "3 1 1 ab", "3 1 1 cd", false},
+	}
+
+	for _, c := range cases {
+		if equal := EqualContent(c.recordType, c.a, c.b); equal != c.expected {
+			t.Errorf(
+				"EqualContent(%s, %q, %q) = %t, expected %t",
+				c.recordType, c.a, c.b, equal, c.expected,
+			)
+		}
+	}
+}
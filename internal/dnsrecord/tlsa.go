@@ -0,0 +1,126 @@
+package dnsrecord
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TLSAContent is the parsed form of a TLSA record's content.
+type TLSAContent struct {
+	Usage        int
+	Selector     int
+	MatchingType int
+	Data         string
+}
+
+// String returns the content in the presentation format sent to the API.
+func (t TLSAContent) String() string {
+	return fmt.Sprintf(
+		"%d %d %d %s", t.Usage, t.Selector, t.MatchingType, t.Data,
+	)
+}
+
+// tlsaDigestLengths are the lengths, in hexadecimal characters, of the
+// association data for the matching types defined in RFC 6698 section 2.1.3.
+var tlsaDigestLengths = map[int]int{
+	1: sha256.Size * 2,
+	2: sha512.Size * 2,
+}
+
+// ParseTLSA parses and validates the content of a TLSA record:
+// `usage selector matching_type data`.
+func ParseTLSA(v string) (TLSAContent, error) {
+	values := strings.Split(v, " ")
+
+	rfc := "Check RFC 6698 sections 2 and 7"
+
+	if len(values) != 4 {
+		return TLSAContent{}, fmt.Errorf(
+			"expected 4 arguments, got: %d. %s",
+			len(values), rfc,
+		)
+	}
+
+	certificateUsage, err := ParseUnsigned(values[0])
+	if err != nil {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Certificate Usage field to be int, got: %s. %s",
+			values[0], rfc,
+		)
+	}
+
+	if certificateUsage < 0 || certificateUsage > 255 {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Certificate Usage field to be between 0 and 255 "+
+				"(inclusive), got: %d. %s",
+			certificateUsage, rfc,
+		)
+	}
+
+	selector, err := ParseUnsigned(values[1])
+	if err != nil {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Selector field to be int, got: %s. %s",
+			values[1], rfc,
+		)
+	}
+
+	if selector < 0 || selector > 255 {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Selector field to be between 0 and 255 (inclusive), "+
+				"got: %d. %s",
+			selector, rfc,
+		)
+	}
+
+	matchingType, err := ParseUnsigned(values[2])
+	if err != nil {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Matching Type field to be int, got: %s. %s",
+			values[2], rfc,
+		)
+	}
+
+	if matchingType < 0 || matchingType > 255 {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Matching Type field to be between 0 and 255 "+
+				"(inclusive), got: %d. %s",
+			matchingType, rfc,
+		)
+	}
+
+	data := values[3]
+	if data == "" {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Certificate Association Data field to not be "+
+				"empty. %s",
+			rfc,
+		)
+	}
+
+	if _, err := hex.DecodeString(data); err != nil {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Certificate Association Data field to be an even "+
+				"number of hexadecimal characters, got: %s. %s",
+			data, rfc,
+		)
+	}
+
+	if length, ok := tlsaDigestLengths[matchingType]; ok && len(data) != length {
+		return TLSAContent{}, fmt.Errorf(
+			"expected Certificate Association Data field to be %d "+
+				"hexadecimal characters for Matching Type %d, got: %d. %s",
+			length, matchingType, len(data), rfc,
+		)
+	}
+
+	return TLSAContent{
+		Usage:        certificateUsage,
+		Selector:     selector,
+		MatchingType: matchingType,
+		Data:         data,
+	}, nil
+}
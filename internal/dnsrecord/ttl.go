@@ -0,0 +1,52 @@
+package dnsrecord
+
+import (
+	"sort"
+
+	"github.com/Sighery/gonjalla"
+)
+
+// IsValidTTL reports whether Njalla accepts a TTL.
+func IsValidTTL(ttl int) bool {
+	for _, valid := range gonjalla.ValidTTL {
+		if ttl == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NearestTTLs returns the closest valid TTLs below and above `ttl`. Either is
+// 0 if there isn't one.
+func NearestTTLs(ttl int) (lower int, upper int) {
+	valid := append([]int(nil), gonjalla.ValidTTL...)
+	sort.Ints(valid)
+
+	for _, candidate := range valid {
+		if candidate <= ttl {
+			lower = candidate
+		}
+		if candidate >= ttl && upper == 0 {
+			upper = candidate
+		}
+	}
+
+	return lower, upper
+}
+
+// RoundTTL returns the valid TTL nearest to `ttl`, the higher one on ties.
+func RoundTTL(ttl int) int {
+	lower, upper := NearestTTLs(ttl)
+
+	switch {
+	case lower == 0:
+		return upper
+	case upper == 0:
+		return lower
+	case ttl-lower < upper-ttl:
+		return lower
+	}
+
+	return upper
+}
@@ -0,0 +1,22 @@
+package dnsrecord
+
+import "testing"
+
+func TestRoundTTL(t *testing.T) {
+	cases := map[int]int{
+		1:      60,
+		60:     60,
+		179:    60,
+		180:    300,
+		1000:   900,
+		2249:   900,
+		2250:   3600,
+		100000: 86400,
+	}
+
+	for ttl, expected := range cases {
+		if rounded := RoundTTL(ttl); rounded != expected {
+			t.Errorf("RoundTTL(%d) = %d, expected %d", ttl, rounded, expected)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package dnsrecord
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TXTMaxStringLength is the maximum length of a single character-string in a
+// TXT record, as defined in RFC 1035 section 3.3.
+const TXTMaxStringLength = 255
+
+// TXTMaxLength is the maximum length of the text of a TXT record: the 65535
+// bytes of RDATA, minus the length byte of each character-string.
+const TXTMaxLength = 65535 / (TXTMaxStringLength + 1) * TXTMaxStringLength
+
+// ParseTXT returns the text of a TXT record's content. Content made of one
+// or more quoted character-strings, like `"v=DKIM1; " "p=..."`, is unquoted
+// and concatenated, handling `\"`, `\\` and `\DDD` escapes. Any other content
+// is the text itself.
+func ParseTXT(content string) (string, error) {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, `"`) || !strings.HasSuffix(trimmed, `"`) ||
+		len(trimmed) < 2 {
+		return content, nil
+	}
+
+	var text strings.Builder
+	i := 0
+	for i < len(trimmed) {
+		if trimmed[i] == ' ' || trimmed[i] == '\t' {
+			i++
+			continue
+		}
+		if trimmed[i] != '"' {
+			// Not a list of quoted strings after all, like `"a" b "c"`.
+			return content, nil
+		}
+
+		length := 0
+		closed := false
+		for i++; i < len(trimmed); i++ {
+			c := trimmed[i]
+			if c == '"' {
+				closed = true
+				i++
+				break
+			}
+
+			if c == '\\' && i+1 < len(trimmed) {
+				if i+3 < len(trimmed) &&
+					isDecimalEscape(trimmed[i+1:i+4]) {
+					value := decimalEscapeValue(trimmed[i+1 : i+4])
+					if value > 255 {
+						return "", fmt.Errorf(
+							"invalid escape \\%s in TXT content",
+							trimmed[i+1:i+4],
+						)
+					}
+					c = byte(value)
+					i += 3
+				} else {
+					i++
+					c = trimmed[i]
+				}
+			}
+
+			text.WriteByte(c)
+			length++
+		}
+
+		if !closed {
+			return content, nil
+		}
+		if length > TXTMaxStringLength {
+			return "", fmt.Errorf(
+				"expected every quoted string of TXT content to be at most "+
+					"%d bytes, got one of %d bytes. Leave the content "+
+					"unquoted to have it split automatically",
+				TXTMaxStringLength, length,
+			)
+		}
+	}
+
+	return text.String(), nil
+}
+
+// isDecimalEscape reports whether s, the 3 characters after a backslash, are
+// digits making a `\DDD` escape.
+func isDecimalEscape(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decimalEscapeValue returns the value of the digits of a `\DDD` escape.
+func decimalEscapeValue(s string) int {
+	value := 0
+	for i := 0; i < len(s); i++ {
+		value = value*10 + int(s[i]-'0')
+	}
+
+	return value
+}
+
+// FormatTXT returns the content sent to the API for a TXT record. Text that
+// fits in a single character-string is sent as is, longer text is split into
+// quoted character-strings of at most 255 bytes.
+func FormatTXT(content string) string {
+	text, err := ParseTXT(content)
+	if err != nil || len(text) <= TXTMaxStringLength {
+		return content
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	var chunks []string
+	for len(text) > 0 {
+		size := TXTMaxStringLength
+		if len(text) < size {
+			size = len(text)
+		}
+
+		chunks = append(chunks, `"`+escaper.Replace(text[:size])+`"`)
+		text = text[size:]
+	}
+
+	return strings.Join(chunks, " ")
+}
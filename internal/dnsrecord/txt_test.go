@@ -0,0 +1,44 @@
+package dnsrecord
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTXT(t *testing.T) {
+	cases := map[string]string{
+		"v=spf1 -all":         "v=spf1 -all",
+		`"v=spf1 -all"`:       "v=spf1 -all",
+		`"v=spf1 " "-all"`:    "v=spf1 -all",
+		`"say \"hi\"" "\\o/"`: `say "hi"\o/`,
+		`"\065\066"`:          "AB",
+		`"a" b "c"`:           `"a" b "c"`,
+		`"unterminated`:       `"unterminated`,
+		`"`:                   `"`,
+		`he said "hi"`:        `he said "hi"`,
+		`"" ""`:               "",
+	}
+
+	for content, expected := range cases {
+		text, err := ParseTXT(content)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", content, err)
+		}
+		if text != expected {
+			t.Fatalf("Parsed %q as %q, expected %q", content, text, expected)
+		}
+	}
+
+	for _, content := range []string{
+		`"` + strings.Repeat("a", 256) + `"`,
+		`"\256"`,
+	} {
+		if _, err := ParseTXT(content); err == nil {
+			t.Fatalf("Unexpected success for %q", content)
+		}
+	}
+
+	if FormatTXT("short") != "short" {
+		t.Fatal("Expected short content to be sent as is")
+	}
+}
@@ -0,0 +1,168 @@
+package njallatest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSServer is a fake authoritative nameserver answering queries over UDP on
+// the loopback interface. Create it with `NewDNSServer` and point a resolver
+// at its `Addr`.
+type DNSServer struct {
+	// Addr is the `host:port` address the server listens on.
+	Addr string
+
+	conn net.PacketConn
+
+	mu      sync.Mutex
+	records map[string][]dnsmessage.Resource
+	queries int
+}
+
+// NewDNSServer starts a fake nameserver without any records. The caller must
+// call `Close` when done.
+func NewDNSServer() *DNSServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		panic("njallatest: failed to listen on a port: " + err.Error())
+	}
+
+	s := &DNSServer{
+		Addr:    conn.LocalAddr().String(),
+		conn:    conn,
+		records: map[string][]dnsmessage.Resource{},
+	}
+	go s.serve()
+
+	return s
+}
+
+// Close stops the server.
+func (s *DNSServer) Close() {
+	s.conn.Close()
+}
+
+// Set replaces the records served at `name`, a fully qualified name with or
+// without its trailing dot. Without any `bodies`, the name stops existing.
+func (s *DNSServer) Set(name string, bodies ...dnsmessage.ResourceBody) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := dnsKey(name)
+	if len(bodies) == 0 {
+		delete(s.records, key)
+		return
+	}
+
+	resources := make([]dnsmessage.Resource, 0, len(bodies))
+	for _, body := range bodies {
+		resources = append(resources, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{
+				Name:  dnsmessage.MustNewName(key + "."),
+				Type:  bodyType(body),
+				Class: dnsmessage.ClassINET,
+				TTL:   60,
+			},
+			Body: body,
+		})
+	}
+	s.records[key] = resources
+}
+
+// Queries returns the number of queries answered so far.
+func (s *DNSServer) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queries
+}
+
+func (s *DNSServer) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		response, err := s.answer(buf[:n])
+		if err != nil {
+			continue
+		}
+		s.conn.WriteTo(response, addr)
+	}
+}
+
+// answer returns the response to a packed query, with the records of the
+// queried name and type, or NXDOMAIN if the name has no records at all.
+func (s *DNSServer) answer(query []byte) ([]byte, error) {
+	var request dnsmessage.Message
+	if err := request.Unpack(query); err != nil {
+		return nil, err
+	}
+
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:            request.ID,
+			Response:      true,
+			Authoritative: true,
+		},
+		Questions: request.Questions,
+	}
+
+	s.mu.Lock()
+	s.queries++
+	if len(request.Questions) != 1 {
+		response.RCode = dnsmessage.RCodeFormatError
+	} else {
+		question := request.Questions[0]
+		resources, ok := s.records[dnsKey(question.Name.String())]
+		if !ok {
+			response.RCode = dnsmessage.RCodeNameError
+		}
+		for _, resource := range resources {
+			if resource.Header.Type == question.Type {
+				response.Answers = append(response.Answers, resource)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	return response.Pack()
+}
+
+// dnsKey returns the key of `name` in the records of the server.
+func dnsKey(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// bodyType returns the type of the record of a resource body.
+func bodyType(body dnsmessage.ResourceBody) dnsmessage.Type {
+	switch b := body.(type) {
+	case *dnsmessage.AResource:
+		return dnsmessage.TypeA
+	case *dnsmessage.AAAAResource:
+		return dnsmessage.TypeAAAA
+	case *dnsmessage.CNAMEResource:
+		return dnsmessage.TypeCNAME
+	case *dnsmessage.MXResource:
+		return dnsmessage.TypeMX
+	case *dnsmessage.NSResource:
+		return dnsmessage.TypeNS
+	case *dnsmessage.PTRResource:
+		return dnsmessage.TypePTR
+	case *dnsmessage.SOAResource:
+		return dnsmessage.TypeSOA
+	case *dnsmessage.SRVResource:
+		return dnsmessage.TypeSRV
+	case *dnsmessage.TXTResource:
+		return dnsmessage.TypeTXT
+	case *dnsmessage.UnknownResource:
+		return b.Type
+	}
+
+	panic("njallatest: unsupported resource body")
+}
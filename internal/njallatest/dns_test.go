@@ -0,0 +1,89 @@
+package njallatest
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// testDNSQuery sends a query for `name` and `qtype` to the server and
+// returns its response.
+func testDNSQuery(
+	t *testing.T, server *DNSServer, name string, qtype dnsmessage.Type,
+) dnsmessage.Message {
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42},
+		Questions: []dnsmessage.Question{
+			{
+				Name:  dnsmessage.MustNewName(name),
+				Type:  qtype,
+				Class: dnsmessage.ClassINET,
+			},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write(packed); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != 42 || !response.Authoritative {
+		t.Fatalf("Unexpected response header %v", response.Header)
+	}
+
+	return response
+}
+
+func TestDNSServer(t *testing.T) {
+	server := NewDNSServer()
+	t.Cleanup(server.Close)
+
+	server.Set(
+		"WWW.testing.com.",
+		&dnsmessage.AResource{A: [4]byte{1, 1, 1, 1}},
+		&dnsmessage.TXTResource{TXT: []string{"token"}},
+	)
+
+	response := testDNSQuery(t, server, "www.testing.com.", dnsmessage.TypeA)
+	if response.RCode != dnsmessage.RCodeSuccess || len(response.Answers) != 1 {
+		t.Fatalf("Expected one A record, got %v", response)
+	}
+
+	response = testDNSQuery(t, server, "www.testing.com.", dnsmessage.TypeMX)
+	if response.RCode != dnsmessage.RCodeSuccess || len(response.Answers) != 0 {
+		t.Fatalf("Expected no MX records, got %v", response)
+	}
+
+	server.Set("www.testing.com")
+
+	response = testDNSQuery(t, server, "www.testing.com.", dnsmessage.TypeA)
+	if response.RCode != dnsmessage.RCodeNameError {
+		t.Fatalf("Expected NXDOMAIN, got %v", response.RCode)
+	}
+
+	if server.Queries() != 3 {
+		t.Fatalf("Expected 3 queries, got %d", server.Queries())
+	}
+}
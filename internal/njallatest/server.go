@@ -0,0 +1,503 @@
+// Package njallatest provides an in-process fake of the Njalla JSON-RPC API
+// for tests, in the spirit of `net/http/httptest`.
+//
+// The fake keeps domains and records in memory and implements the API
+// methods this repository uses. Tests can inject latency, queue errors for
+// specific methods and enable rate limiting to exercise the failure paths of
+// the client and the provider.
+package njallatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sighery/gonjalla"
+)
+
+// Error codes returned by the fake in JSON-RPC error responses.
+const (
+	CodeInvalidRequest = 400
+	CodePermission     = 403
+	CodeNotFound       = 404
+	CodeRateLimited    = 429
+)
+
+// Server is a fake Njalla API. Create it with `NewServer` and point a client
+// at its `URL`.
+type Server struct {
+	*httptest.Server
+
+	// Token is the only API token the fake accepts.
+	Token string
+
+	mu        sync.Mutex
+	domains   map[string]*domain
+	nextID    int
+	latency   time.Duration
+	failures  map[string][]*rpcError
+	rateLimit int
+	rateEvery time.Duration
+	requests  []time.Time
+	calls     []string
+}
+
+type domain struct {
+	name    string
+	records []gonjalla.Record
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewServer starts a fake accepting the given token, without any domains.
+// The caller must call `Close` when done.
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:    token,
+		domains:  map[string]*domain{},
+		nextID:   1000,
+		failures: map[string][]*rpcError{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// AddDomain registers a domain with no records.
+func (s *Server) AddDomain(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[name]; !ok {
+		s.domains[name] = &domain{name: name}
+	}
+}
+
+// AddRecord seeds a record into a registered domain, bypassing the API. The
+// record is given a new ID, and returned.
+func (s *Server) AddRecord(domainName string, record gonjalla.Record) gonjalla.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.domains[domainName]
+	if !ok {
+		panic(fmt.Sprintf("njallatest: unknown domain %s", domainName))
+	}
+
+	record.ID = s.newID()
+	d.records = append(d.records, record)
+
+	return record
+}
+
+// Records returns a copy of the records currently in a domain.
+func (s *Server) Records(domainName string) []gonjalla.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.domains[domainName]
+	if !ok {
+		return nil
+	}
+
+	records := make([]gonjalla.Record, len(d.records))
+	copy(records, d.records)
+
+	return records
+}
+
+// SetLatency delays every response by the given duration. Requests whose
+// client goes away during the delay are dropped without being applied.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+}
+
+// FailNext makes the next call of `method` fail with the given JSON-RPC
+// error, without being applied. Calling it several times queues failures.
+func (s *Server) FailNext(method string, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = append(
+		s.failures[method], &rpcError{Code: code, Message: message},
+	)
+}
+
+// FailAfter lets the next `calls` calls of `method` through, and makes the
+// one after them fail with the given JSON-RPC error, without being applied.
+func (s *Server) FailAfter(method string, calls int, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < calls; i++ {
+		s.failures[method] = append(s.failures[method], nil)
+	}
+	s.failures[method] = append(
+		s.failures[method], &rpcError{Code: code, Message: message},
+	)
+}
+
+// SetRateLimit makes the fake answer with HTTP 429 once more than `requests`
+// requests arrive within `every`. A zero `requests` disables rate limiting.
+func (s *Server) SetRateLimit(requests int, every time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimit = requests
+	s.rateEvery = every
+	s.requests = nil
+}
+
+// Calls returns the methods of every request received so far, in order,
+// including rejected ones.
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := make([]string, len(s.calls))
+	copy(calls, s.calls)
+
+	return calls
+}
+
+// CallCount returns how many requests for `method` were received so far.
+func (s *Server) CallCount(method string) int {
+	count := 0
+	for _, call := range s.Calls() {
+		if call == method {
+			count++
+		}
+	}
+
+	return count
+}
+
+type request struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, req.Method)
+	latency := s.latency
+	limited := s.rateLimited()
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if limited {
+		w.WriteHeader(http.StatusTooManyRequests)
+		writeJSON(w, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"error": &rpcError{
+				Code: CodeRateLimited, Message: "Too many requests",
+			},
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Njalla "+s.Token {
+		writeError(w, &rpcError{Code: CodePermission, Message: "Permission denied"})
+		return
+	}
+
+	result, rpcErr := s.dispatch(req)
+	if rpcErr != nil {
+		writeError(w, rpcErr)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"result":  result,
+	})
+}
+
+// rateLimited records a request and reports whether it goes over the rate
+// limit. Must be called with the lock held.
+func (s *Server) rateLimited() bool {
+	if s.rateLimit <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	recent := s.requests[:0]
+	for _, t := range s.requests {
+		if now.Sub(t) < s.rateEvery {
+			recent = append(recent, t)
+		}
+	}
+	s.requests = append(recent, now)
+
+	return len(s.requests) > s.rateLimit
+}
+
+func (s *Server) dispatch(req request) (interface{}, *rpcError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A nil failure, queued by `FailAfter`, lets the call through.
+	if queued := s.failures[req.Method]; len(queued) > 0 {
+		s.failures[req.Method] = queued[1:]
+		if queued[0] != nil {
+			return nil, queued[0]
+		}
+	}
+
+	var params map[string]interface{}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalid("invalid params: %s", err)
+		}
+	}
+
+	switch req.Method {
+	case "list-domains":
+		return s.listDomains(), nil
+	case "get-domain":
+		d, rpcErr := s.domain(params)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		return map[string]interface{}{"name": d.name, "status": "active"}, nil
+	case "list-records":
+		d, rpcErr := s.domain(params)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		records := d.records
+		if records == nil {
+			records = []gonjalla.Record{}
+		}
+		return map[string]interface{}{"records": records}, nil
+	case "add-record":
+		return s.addRecord(params)
+	case "edit-record":
+		return s.editRecord(params)
+	case "remove-record":
+		return s.removeRecord(params)
+	}
+
+	return nil, &rpcError{
+		Code:    CodeNotFound,
+		Message: fmt.Sprintf("Method %s not found", req.Method),
+	}
+}
+
+func (s *Server) listDomains() interface{} {
+	names := make([]string, 0, len(s.domains))
+	for name := range s.domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	domains := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		domains = append(domains, map[string]interface{}{
+			"name": name, "status": "active",
+		})
+	}
+
+	return map[string]interface{}{"domains": domains}
+}
+
+func (s *Server) domain(params map[string]interface{}) (*domain, *rpcError) {
+	name, _ := params["domain"].(string)
+	if name == "" {
+		return nil, invalid("missing domain")
+	}
+
+	d, ok := s.domains[name]
+	if !ok {
+		return nil, &rpcError{
+			Code:    CodeNotFound,
+			Message: fmt.Sprintf("Domain %s not found", name),
+		}
+	}
+
+	return d, nil
+}
+
+func (s *Server) addRecord(params map[string]interface{}) (interface{}, *rpcError) {
+	d, rpcErr := s.domain(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	record := gonjalla.Record{}
+	if rpcErr := applyParams(&record, params); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if record.Type == "" {
+		return nil, invalid("missing type")
+	}
+	if record.Content == "" {
+		return nil, invalid("missing content")
+	}
+	if record.Name == "" {
+		record.Name = "@"
+	}
+	if record.TTL == 0 {
+		record.TTL = 10800
+	}
+	if record.Type == "MX" && record.Priority == nil {
+		return nil, invalid("missing prio for MX record")
+	}
+
+	record.ID = s.newID()
+	d.records = append(d.records, record)
+
+	return record, nil
+}
+
+func (s *Server) editRecord(params map[string]interface{}) (interface{}, *rpcError) {
+	d, rpcErr := s.domain(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	i, rpcErr := d.find(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	record := d.records[i]
+	previousType := record.Type
+	if rpcErr := applyParams(&record, params); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if record.Type != previousType {
+		return nil, invalid("record type cannot be changed")
+	}
+	d.records[i] = record
+
+	return record, nil
+}
+
+func (s *Server) removeRecord(params map[string]interface{}) (interface{}, *rpcError) {
+	d, rpcErr := s.domain(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	i, rpcErr := d.find(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	d.records = append(d.records[:i], d.records[i+1:]...)
+
+	return map[string]interface{}{}, nil
+}
+
+func (d *domain) find(params map[string]interface{}) (int, *rpcError) {
+	id := fmt.Sprint(params["id"])
+	for i, record := range d.records {
+		if record.ID == id {
+			return i, nil
+		}
+	}
+
+	return 0, &rpcError{
+		Code:    CodeNotFound,
+		Message: fmt.Sprintf("Record %s not found in %s", id, d.name),
+	}
+}
+
+// applyParams sets every record field present in the params of a request.
+func applyParams(record *gonjalla.Record, params map[string]interface{}) *rpcError {
+	for key, value := range params {
+		switch key {
+		case "type":
+			record.Type = fmt.Sprint(value)
+		case "name":
+			record.Name = fmt.Sprint(value)
+		case "content":
+			record.Content = fmt.Sprint(value)
+		case "ttl":
+			ttl, err := toInt(value)
+			if err != nil {
+				return invalid("invalid ttl: %s", err)
+			}
+			record.TTL = ttl
+		case "prio":
+			prio, err := toInt(value)
+			if err != nil {
+				return invalid("invalid prio: %s", err)
+			}
+			record.Priority = &prio
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func toInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	}
+
+	return 0, fmt.Errorf("unexpected value %v", value)
+}
+
+func invalid(format string, args ...interface{}) *rpcError {
+	return &rpcError{
+		Code:    CodeInvalidRequest,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func writeError(w http.ResponseWriter, rpcErr *rpcError) {
+	writeJSON(w, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"error":   rpcErr,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,184 @@
+package njallatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Sighery/gonjalla"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+)
+
+func newTestServer(t *testing.T) (*Server, *api.Client) {
+	server := NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	return server, client
+}
+
+func TestRecordLifecycle(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	priority := 10
+	saved, err := client.AddRecord(ctx, "testing.com", gonjalla.Record{
+		Type:     "MX",
+		Name:     "@",
+		Content:  "mail.testing.com",
+		TTL:      3600,
+		Priority: &priority,
+	})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if saved.ID == "" {
+		t.Fatal("Missing ID in saved record")
+	}
+
+	saved.Content = "mail2.testing.com"
+	if err := client.EditRecord(ctx, "testing.com", saved); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	records, err := client.ListRecords(ctx, "testing.com")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if len(records) != 1 || records[0].Content != "mail2.testing.com" {
+		t.Fatalf("Unexpected records %v", records)
+	}
+	if records[0].Priority == nil || *records[0].Priority != 10 {
+		t.Fatalf("Unexpected priority in %v", records[0])
+	}
+
+	if err := client.RemoveRecord(ctx, "testing.com", saved.ID); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected no records, got %v", records)
+	}
+}
+
+func TestEditRecordTypeChange(t *testing.T) {
+	server, client := newTestServer(t)
+
+	saved := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	saved.Type = "AAAA"
+
+	err := client.EditRecord(context.Background(), "testing.com", saved)
+
+	var rpcErr *api.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidRequest {
+		t.Fatalf("Expected invalid request error, got %v", err)
+	}
+}
+
+func TestUnknownDomain(t *testing.T) {
+	_, client := newTestServer(t)
+
+	_, err := client.ListRecords(context.Background(), "unknown.com")
+
+	var rpcErr *api.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeNotFound {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+}
+
+func TestWrongToken(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	client := api.NewClient("wrong-token")
+	client.Endpoint = server.URL
+
+	_, err := client.ListRecords(context.Background(), "testing.com")
+
+	var rpcErr *api.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodePermission {
+		t.Fatalf("Expected permission error, got %v", err)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	server.FailNext("list-records", 500, "Internal error")
+
+	if _, err := client.ListRecords(ctx, "testing.com"); err == nil {
+		t.Fatal("Unexpected success")
+	}
+	if _, err := client.ListRecords(ctx, "testing.com"); err != nil {
+		t.Fatalf("Queued failure should only apply once: %q", err)
+	}
+	if count := server.CallCount("list-records"); count != 2 {
+		t.Fatalf("Expected 2 calls, got %d", count)
+	}
+}
+
+func TestFailAfter(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	server.FailAfter("list-records", 1, 500, "Internal error")
+
+	if _, err := client.ListRecords(ctx, "testing.com"); err != nil {
+		t.Fatalf("First call should go through: %q", err)
+	}
+	if _, err := client.ListRecords(ctx, "testing.com"); err == nil {
+		t.Fatal("Unexpected success")
+	}
+	if _, err := client.ListRecords(ctx, "testing.com"); err != nil {
+		t.Fatalf("Queued failure should only apply once: %q", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	server.SetRateLimit(2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListRecords(ctx, "testing.com"); err != nil {
+			t.Fatalf("%q", err)
+		}
+	}
+
+	_, err := client.ListRecords(ctx, "testing.com")
+
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected HTTP 429, got %v", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	server, client := newTestServer(t)
+
+	server.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	saved, err := client.AddRecord(ctx, "testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v %v", saved, err)
+	}
+
+	// Give the server a moment to notice the client went away.
+	time.Sleep(50 * time.Millisecond)
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Aborted request was applied: %v", records)
+	}
+}
@@ -0,0 +1,568 @@
+// Package libdns manages Njalla records through the interfaces of libdns
+// (github.com/libdns/libdns), the DNS provider API used by Caddy and other
+// Go DNS tooling.
+//
+// `Provider` implements libdns v1's `RecordGetter`, `RecordAppender`,
+// `RecordSetter` and `RecordDeleter`, so it can be given to any libdns
+// consumer as is. Records are returned as the typed records of libdns, like
+// `libdns.Address` or `libdns.TXT`, with their Njalla ID, a string, as their
+// `ProviderData`. Types libdns doesn't have a struct for, like PTR or TLSA,
+// are returned as `libdns.RR`, without their ID.
+//
+// Zones are fully qualified, like `example.com.`, and record names are
+// relative to them, with `@` for the apex. Records are validated, named and
+// encoded the way the Terraform provider's resources do.
+package libdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// DefaultTTL is the TTL of records given without one, in seconds. It's the
+// Terraform provider's default. Njalla can't serve records with a TTL of
+// zero, so a zero TTL gets this one too.
+const DefaultTTL = 10800
+
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)
+
+// Provider manages the records of the Njalla domains of an account. The zero
+// value is usable once `APIToken` is set. Its methods are safe for
+// concurrent use, and run one at a time.
+type Provider struct {
+	// APIToken is the Njalla API token.
+	APIToken string `json:"api_token,omitempty"`
+	// Endpoint is the Njalla API endpoint. Defaults to Njalla's.
+	Endpoint string `json:"endpoint,omitempty"`
+	// HTTPClient sends the API requests. Defaults to a new `http.Client`.
+	HTTPClient *http.Client `json:"-"`
+
+	mu     sync.Mutex
+	client *api.Client
+}
+
+// NewProviderClient returns a provider making its calls through an existing
+// client, sharing its rate limiter.
+func NewProviderClient(client *api.Client) *Provider {
+	return &Provider{client: client}
+}
+
+// apiClient returns the client of the provider, creating it on first use.
+func (p *Provider) apiClient() (*api.Client, error) {
+	if p.client != nil {
+		return p.client, nil
+	}
+
+	if p.APIToken == "" {
+		return nil, errors.New("njalla: the API token is missing")
+	}
+
+	client := api.NewClient(p.APIToken)
+	if p.Endpoint != "" {
+		client.Endpoint = p.Endpoint
+	}
+	if p.HTTPClient != nil {
+		client.HTTPClient = p.HTTPClient
+	}
+	p.client = client
+
+	return client, nil
+}
+
+// GetRecords returns every record of `zone`.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	domain, records, err := p.listRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		result = append(result, fromNjalla(record, domain))
+	}
+
+	return result, nil
+}
+
+// AppendRecords adds `records` to `zone`, and returns them as saved. Every
+// record is checked before any is added.
+func (p *Provider) AppendRecords(
+	ctx context.Context, zone string, records []libdns.Record,
+) ([]libdns.Record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	client, err := p.apiClient()
+	if err != nil {
+		return nil, err
+	}
+
+	domain := zoneDomain(zone)
+	added, err := toNjallaRecords(records, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []libdns.Record
+	for _, record := range added {
+		saved, err := client.AddRecord(ctx, domain, record)
+		if err != nil {
+			return result, fmt.Errorf(
+				"njalla: adding %s record %s to %s failed: %w",
+				record.Type, record.Name, domain, err,
+			)
+		}
+		result = append(result, fromNjalla(saved, domain))
+	}
+
+	return result, nil
+}
+
+// SetRecords makes the records of each type and name in `records` be
+// exactly those given, and returns them as saved. Existing records with an
+// equivalent value and priority are kept, and edited if their TTL differs,
+// missing ones are added and any other record of that type and name is
+// removed. Records of other types and names aren't touched.
+func (p *Provider) SetRecords(
+	ctx context.Context, zone string, records []libdns.Record,
+) ([]libdns.Record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	domain, existing, err := p.listRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted, err := toNjallaRecords(records, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []libdns.Record
+	for _, key := range rrsetKeys(wanted, domain) {
+		saved, err := p.setRRSet(
+			ctx, domain,
+			rrset(existing, key, domain),
+			rrset(wanted, key, domain),
+		)
+		for _, record := range saved {
+			result = append(result, fromNjalla(record, domain))
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteRecords removes the records of `zone` matching `records`, and
+// returns those removed. A record with a Njalla ID as its `ProviderData`
+// matches the record with that ID. Otherwise it matches the records with its
+// name, and with its type, value and TTL if they're set. Records matching
+// nothing are ignored.
+func (p *Provider) DeleteRecords(
+	ctx context.Context, zone string, records []libdns.Record,
+) ([]libdns.Record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	domain, existing, err := p.listRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]bool, len(existing))
+
+	var result []libdns.Record
+	for _, record := range records {
+		for i, current := range existing {
+			if removed[i] || !matchDeleted(current, record, domain) {
+				continue
+			}
+
+			if err := p.client.RemoveRecord(ctx, domain, current.ID); err != nil {
+				return result, fmt.Errorf(
+					"njalla: removing %s record %s from %s failed: %w",
+					current.Type, current.Name, domain, err,
+				)
+			}
+			removed[i] = true
+			result = append(result, fromNjalla(current, domain))
+		}
+	}
+
+	return result, nil
+}
+
+// listRecords returns the Njalla domain of `zone`, and its records.
+func (p *Provider) listRecords(
+	ctx context.Context, zone string,
+) (string, []gonjalla.Record, error) {
+	client, err := p.apiClient()
+	if err != nil {
+		return "", nil, err
+	}
+
+	domain := zoneDomain(zone)
+	records, err := client.ListRecords(ctx, domain)
+	if err != nil {
+		return "", nil, fmt.Errorf(
+			"njalla: listing the records of %s failed: %w", domain, err,
+		)
+	}
+
+	return domain, records, nil
+}
+
+// setRRSet reconciles the `existing` records of a type and name with the
+// `wanted` ones, returning the records as saved.
+func (p *Provider) setRRSet(
+	ctx context.Context, domain string, existing, wanted []gonjalla.Record,
+) ([]gonjalla.Record, error) {
+	kept := make([]bool, len(existing))
+
+	var saved []gonjalla.Record
+	for _, record := range wanted {
+		found := false
+		for i, current := range existing {
+			if kept[i] || !dnsrecord.SameRecord(current, record, domain) {
+				continue
+			}
+
+			kept[i], found = true, true
+			if current.TTL == record.TTL {
+				saved = append(saved, current)
+				break
+			}
+
+			record.ID = current.ID
+			if err := p.client.EditRecord(ctx, domain, record); err != nil {
+				return saved, fmt.Errorf(
+					"njalla: editing %s record %s of %s failed: %w",
+					record.Type, record.Name, domain, err,
+				)
+			}
+			saved = append(saved, record)
+			break
+		}
+
+		if found {
+			continue
+		}
+
+		added, err := p.client.AddRecord(ctx, domain, record)
+		if err != nil {
+			return saved, fmt.Errorf(
+				"njalla: adding %s record %s to %s failed: %w",
+				record.Type, record.Name, domain, err,
+			)
+		}
+		saved = append(saved, added)
+	}
+
+	for i, current := range existing {
+		if kept[i] {
+			continue
+		}
+
+		if err := p.client.RemoveRecord(ctx, domain, current.ID); err != nil {
+			return saved, fmt.Errorf(
+				"njalla: removing %s record %s from %s failed: %w",
+				current.Type, current.Name, domain, err,
+			)
+		}
+	}
+
+	return saved, nil
+}
+
+// rrsetKey identifies the records of a type at a name.
+type rrsetKey struct {
+	Type string
+	Name string
+}
+
+// rrsetKeys returns the types and names of `records`, in the order they
+// first appear.
+func rrsetKeys(records []gonjalla.Record, domain string) []rrsetKey {
+	var keys []rrsetKey
+	seen := map[rrsetKey]bool{}
+
+	for _, record := range records {
+		key := rrsetKey{
+			Type: strings.ToUpper(record.Type),
+			Name: strings.ToLower(dnsrecord.NormalizeName(record.Name, domain)),
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// rrset returns the records of `records` with the type and name of `key`.
+func rrset(records []gonjalla.Record, key rrsetKey, domain string) []gonjalla.Record {
+	var set []gonjalla.Record
+	for _, record := range records {
+		if !strings.EqualFold(record.Type, key.Type) ||
+			!strings.EqualFold(dnsrecord.NormalizeName(record.Name, domain), key.Name) {
+			continue
+		}
+
+		set = append(set, record)
+	}
+
+	return set
+}
+
+// matchDeleted reports whether the existing record `current` is matched by
+// `record`, given to `DeleteRecords`.
+func matchDeleted(current gonjalla.Record, record libdns.Record, domain string) bool {
+	if id := recordID(record); id != "" {
+		return current.ID == id
+	}
+
+	rr := record.RR()
+	if rr.Type != "" && !strings.EqualFold(current.Type, rr.Type) {
+		return false
+	}
+
+	if !strings.EqualFold(
+		dnsrecord.NormalizeName(current.Name, domain),
+		dnsrecord.NormalizeName(rr.Name, domain),
+	) {
+		return false
+	}
+
+	if rr.Data != "" {
+		value, priority, err := recordValue(record)
+		if err != nil || !dnsrecord.EqualContent(
+			current.Type, current.Content, dnsrecord.Content(current.Type, value),
+		) {
+			return false
+		}
+
+		if dnsrecord.HasPriority(current.Type) && current.Priority != nil &&
+			*current.Priority != priority {
+			return false
+		}
+	}
+
+	if rr.TTL != 0 && current.TTL != recordTTL(rr.TTL) {
+		return false
+	}
+
+	return true
+}
+
+// zoneDomain returns the Njalla domain of a zone: in lower case ASCII form,
+// without the trailing dot.
+func zoneDomain(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(dnsrecord.ToASCII(zone), "."))
+}
+
+// recordTTL returns the TTL sent to the API for a record's TTL.
+func recordTTL(ttl time.Duration) int {
+	if ttl <= 0 {
+		return DefaultTTL
+	}
+
+	return dnsrecord.RoundTTL(int(ttl / time.Second))
+}
+
+// toNjallaRecords checks `records`, and returns them ready to be sent to the
+// API.
+func toNjallaRecords(records []libdns.Record, domain string) ([]gonjalla.Record, error) {
+	result := make([]gonjalla.Record, 0, len(records))
+	for _, record := range records {
+		converted, err := toNjalla(record, domain)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+// toNjalla checks a record, and returns it ready to be sent to the API, with
+// its name relative to `domain`, its content encoded and its TTL rounded.
+func toNjalla(record libdns.Record, domain string) (gonjalla.Record, error) {
+	rr := record.RR()
+	recordType := strings.ToUpper(rr.Type)
+	name := dnsrecord.NormalizeName(rr.Name, domain)
+
+	value, priority, err := recordValue(record)
+	if err != nil {
+		return gonjalla.Record{}, fmt.Errorf(
+			"njalla: invalid %s record %s: %w", recordType, name, err,
+		)
+	}
+
+	if err := dnsrecord.Validate("value", recordType, value); err != nil {
+		return gonjalla.Record{}, fmt.Errorf(
+			"njalla: invalid %s record %s: %w", recordType, name, err,
+		)
+	}
+
+	converted := gonjalla.Record{
+		ID:      recordID(record),
+		Type:    recordType,
+		Name:    name,
+		Content: dnsrecord.Content(recordType, value),
+		TTL:     recordTTL(rr.TTL),
+	}
+
+	if dnsrecord.HasPriority(recordType) {
+		if !validPriority(priority) {
+			return gonjalla.Record{}, fmt.Errorf(
+				"njalla: invalid %s record %s: expected priority to be one "+
+					"of %v, got: %d",
+				recordType, name, gonjalla.ValidPriority, priority,
+			)
+		}
+		converted.Priority = &priority
+	}
+
+	return converted, nil
+}
+
+// recordValue returns the value of a record, as given to `dnsrecord.Content`,
+// and its priority for MX records. `libdns.RR` records are parsed first, so
+// their data is read like the matching typed record.
+func recordValue(record libdns.Record) (string, int, error) {
+	if rr, ok := record.(libdns.RR); ok {
+		parsed, err := rr.Parse()
+		if err != nil {
+			return "", 0, err
+		}
+		record = parsed
+	}
+
+	switch r := record.(type) {
+	case libdns.Address:
+		return r.IP.String(), 0, nil
+	case libdns.CAA:
+		return dnsrecord.CAAContent{
+			Flags: int(r.Flags), Tag: r.Tag, Value: r.Value,
+		}.String(), 0, nil
+	case libdns.CNAME:
+		return r.Target, 0, nil
+	case libdns.MX:
+		return r.Target, int(r.Preference), nil
+	case libdns.NS:
+		return r.Target, 0, nil
+	case libdns.TXT:
+		return r.Text, 0, nil
+	}
+
+	return record.RR().Data, 0, nil
+}
+
+// recordID returns the Njalla ID of a record given to the provider, kept as
+// its `ProviderData`, or an empty string if it has none.
+func recordID(record libdns.Record) string {
+	var data interface{}
+	switch r := record.(type) {
+	case libdns.Address:
+		data = r.ProviderData
+	case libdns.CAA:
+		data = r.ProviderData
+	case libdns.CNAME:
+		data = r.ProviderData
+	case libdns.MX:
+		data = r.ProviderData
+	case libdns.NS:
+		data = r.ProviderData
+	case libdns.TXT:
+		data = r.ProviderData
+	}
+
+	id, _ := data.(string)
+	return id
+}
+
+// validPriority reports whether Njalla accepts a priority.
+func validPriority(priority int) bool {
+	for _, valid := range gonjalla.ValidPriority {
+		if priority == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fromNjalla returns a record returned by the API, in `domain`, as the typed
+// libdns record of its type. Its Njalla ID is its `ProviderData`.
+func fromNjalla(record gonjalla.Record, domain string) libdns.Record {
+	name := dnsrecord.NormalizeName(record.Name, domain)
+	ttl := time.Duration(record.TTL) * time.Second
+	value := dnsrecord.Value(record.Type, record.Content)
+
+	switch record.Type {
+	case "A", "AAAA":
+		if ip, err := netip.ParseAddr(value); err == nil {
+			return libdns.Address{
+				Name: name, TTL: ttl, IP: ip, ProviderData: record.ID,
+			}
+		}
+	case "CAA":
+		if parsed, err := dnsrecord.ParseCAA(value); err == nil {
+			return libdns.CAA{
+				Name:         name,
+				TTL:          ttl,
+				Flags:        uint8(parsed.Flags),
+				Tag:          parsed.Tag,
+				Value:        parsed.Value,
+				ProviderData: record.ID,
+			}
+		}
+	case "CNAME":
+		return libdns.CNAME{
+			Name: name, TTL: ttl, Target: value, ProviderData: record.ID,
+		}
+	case "MX":
+		mx := libdns.MX{
+			Name: name, TTL: ttl, Target: value, ProviderData: record.ID,
+		}
+		if record.Priority != nil && *record.Priority > 0 {
+			mx.Preference = uint16(*record.Priority)
+		}
+		return mx
+	case "NS":
+		return libdns.NS{
+			Name: name, TTL: ttl, Target: value, ProviderData: record.ID,
+		}
+	case "TXT":
+		return libdns.TXT{
+			Name: name, TTL: ttl, Text: value, ProviderData: record.ID,
+		}
+	}
+
+	return libdns.RR{Name: name, TTL: ttl, Type: record.Type, Data: value}
+}
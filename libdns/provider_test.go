@@ -0,0 +1,246 @@
+package libdns
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *njallatest.Server) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	return &Provider{APIToken: "test-token", Endpoint: server.URL}, server
+}
+
+// testRecords returns the records of the fake as `type name content ttl`
+// lines, sorted.
+func testRecords(server *njallatest.Server) []string {
+	var lines []string
+	for _, record := range server.Records("testing.com") {
+		line := record.Type + " " + record.Name + " " + record.Content
+		if record.Priority != nil {
+			line += " " + strconv.Itoa(*record.Priority)
+		}
+		lines = append(lines, line+" "+(time.Duration(record.TTL)*time.Second).String())
+	}
+	sort.Strings(lines)
+
+	return lines
+}
+
+func TestGetRecords(t *testing.T) {
+	p, server := newTestProvider(t)
+
+	priority := 10
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "MX", Name: "@", Content: "mail.testing.com.", TTL: 3600,
+		Priority: &priority,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "TXT", Name: "www.testing.com.", Content: `"v=spf1 " "-all"`,
+		TTL: 300,
+	})
+
+	records, err := p.GetRecords(context.Background(), "Testing.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].RR().Type < records[j].RR().Type
+	})
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", records)
+	}
+
+	mx, ok := records[0].(libdns.MX)
+	if !ok || mx.ProviderData == "" {
+		t.Fatalf("Expected an MX record with an ID, got %+v", records[0])
+	}
+	mx.ProviderData = nil
+	expectedMX := libdns.MX{
+		Name: "@", TTL: time.Hour, Preference: 10, Target: "mail.testing.com.",
+	}
+	if mx != expectedMX {
+		t.Fatalf("Got %+v, expected %+v", mx, expectedMX)
+	}
+
+	txt, ok := records[1].(libdns.TXT)
+	if !ok || txt.ProviderData == "" {
+		t.Fatalf("Expected a TXT record with an ID, got %+v", records[1])
+	}
+	txt.ProviderData = nil
+	expectedTXT := libdns.TXT{Name: "www", TTL: 5 * time.Minute, Text: "v=spf1 -all"}
+	if txt != expectedTXT {
+		t.Fatalf("Got %+v, expected %+v", txt, expectedTXT)
+	}
+}
+
+func TestAppendRecords(t *testing.T) {
+	p, server := newTestProvider(t)
+
+	long := strings.Repeat("a", 300)
+	added, err := p.AppendRecords(context.Background(), "testing.com.", []libdns.Record{
+		libdns.TXT{
+			Name: "_acme-challenge.www.testing.com.", Text: long,
+			TTL: 2 * time.Minute,
+		},
+		libdns.MX{Name: "@", Target: "mail.bücher.example.", Preference: 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(added) != 2 {
+		t.Fatalf("Unexpected records added %+v", added)
+	}
+	txt, ok := added[0].(libdns.TXT)
+	if !ok || txt.ProviderData == "" || txt.Text != long ||
+		txt.Name != "_acme-challenge.www" || txt.TTL != time.Minute {
+		t.Fatalf("Unexpected records added %+v", added)
+	}
+
+	records := server.Records("testing.com")
+	for _, record := range records {
+		switch record.Type {
+		case "TXT":
+			if !strings.HasPrefix(record.Content, `"`) {
+				t.Fatalf("Expected the long text to be split, got %q", record.Content)
+			}
+		case "MX":
+			if record.Content != "mail.xn--bcher-kva.example." ||
+				record.TTL != DefaultTTL || *record.Priority != 20 {
+				t.Fatalf("Unexpected MX record %+v", record)
+			}
+		}
+	}
+}
+
+func TestAppendRecordsInvalid(t *testing.T) {
+	p, server := newTestProvider(t)
+
+	for _, records := range [][]libdns.Record{
+		{
+			libdns.Address{Name: "www", IP: netip.MustParseAddr("1.2.3.4")},
+			libdns.RR{Type: "A", Name: "www", Data: "www"},
+		},
+		{libdns.MX{Name: "@", Target: "mail.testing.com.", Preference: 15}},
+		{libdns.Address{Name: "www"}},
+		{libdns.RR{Type: "SRV", Name: "_sip._udp", Data: "10 5 5060 sip.testing.com."}},
+	} {
+		if _, err := p.AppendRecords(context.Background(), "testing.com.", records); err == nil {
+			t.Fatalf("Expected %+v to fail", records)
+		}
+	}
+
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected no records added, got %v", records)
+	}
+}
+
+func TestSetRecords(t *testing.T) {
+	p, server := newTestProvider(t)
+
+	kept := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "2.2.2.2", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "AAAA", Name: "www", Content: "2001:db8::1", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "mail", Content: "2.2.2.2", TTL: 3600,
+	})
+
+	set, err := p.SetRecords(context.Background(), "testing.com.", []libdns.Record{
+		libdns.Address{Name: "WWW", IP: netip.MustParseAddr("1.1.1.1"), TTL: time.Hour},
+		libdns.RR{Type: "A", Name: "www", Data: "3.3.3.3", TTL: time.Hour},
+		libdns.TXT{Name: "@", Text: "hello", TTL: 5 * time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(set) != 3 || recordID(set[0]) != kept.ID {
+		t.Fatalf("Expected the equivalent record to be kept, got %+v", set)
+	}
+
+	expected := []string{
+		"A mail 2.2.2.2 1h0m0s",
+		"A www 1.1.1.1 1h0m0s",
+		"A www 3.3.3.3 1h0m0s",
+		"AAAA www 2001:db8::1 1h0m0s",
+		"TXT @ hello 5m0s",
+	}
+	if records := testRecords(server); strings.Join(records, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Got records %v, expected %v", records, expected)
+	}
+
+	// Changing only the TTL edits the record in place.
+	set, err = p.SetRecords(context.Background(), "testing.com.", []libdns.Record{
+		libdns.TXT{Name: "@", Text: "hello", TTL: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.CallCount("edit-record") != 1 || set[0].RR().TTL != time.Minute {
+		t.Fatalf("Expected the TTL to be edited, got %+v", set)
+	}
+}
+
+func TestDeleteRecords(t *testing.T) {
+	p, server := newTestProvider(t)
+
+	byID := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "TXT", Name: "_acme-challenge", Content: `"token"`, TTL: 60,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "TXT", Name: "_acme-challenge", Content: "other", TTL: 60,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "mail", Content: "2.2.2.2", TTL: 3600,
+	})
+
+	deleted, err := p.DeleteRecords(context.Background(), "testing.com.", []libdns.Record{
+		libdns.Address{Name: "www", ProviderData: byID.ID},
+		libdns.TXT{Name: "_acme-challenge", Text: "token"},
+		libdns.RR{Type: "A", Name: "mail", TTL: time.Minute},
+		libdns.RR{Type: "CNAME", Name: "missing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleted) != 2 || recordID(deleted[0]) != byID.ID ||
+		deleted[1].RR().Data != "token" {
+		t.Fatalf("Unexpected records deleted %+v", deleted)
+	}
+
+	expected := []string{"A mail 2.2.2.2 1h0m0s", "TXT _acme-challenge other 1m0s"}
+	if records := testRecords(server); strings.Join(records, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Got records %v, expected %v", records, expected)
+	}
+}
+
+func TestProviderMissingToken(t *testing.T) {
+	p := &Provider{}
+	if _, err := p.GetRecords(context.Background(), "testing.com."); err == nil {
+		t.Fatal("Expected a missing token to fail")
+	}
+}
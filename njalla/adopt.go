@@ -0,0 +1,200 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// If an apply is interrupted after a record is added but before it's saved
+// to the state, the next apply adds it again. With `adopt_existing`, Create
+// first looks for a record with the same type, name and content, and takes
+// its ID instead of adding a new one.
+
+// adoptExistingSchema returns the schema of the `adopt_existing` attribute
+// shared by every record resource. It's only read on create, so Update skips
+// changes to it alone, as listed in `settingsAttributes`.
+func adoptExistingSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Description: "Adopt an identical existing record on create instead of " +
+			"adding a new one. Defaults to the provider's adopt_existing.",
+	}
+}
+
+// adoptExisting reports whether Create should adopt an identical existing
+// record for the resource described by `d`. The resource's `adopt_existing`
+// takes precedence over the provider's when set.
+func (c *Config) adoptExisting(d *schema.ResourceData) bool {
+	raw := d.GetRawConfig()
+	if raw.IsNull() || !raw.IsKnown() {
+		return c.AdoptExisting
+	}
+
+	value := raw.GetAttr("adopt_existing")
+	if value.IsNull() || !value.IsKnown() {
+		return c.AdoptExisting
+	}
+
+	return value.True()
+}
+
+// createRecord adds a record, or with `adopt_existing` returns an identical
+// existing one. An adopted record with a different TTL is edited to use the
+// new one. Adoption is logged and reported as a warning diagnostic. With an
+// `owner_id`, records owned by another owner are refused, and the record's
+// ownership is claimed. With `cname_conflicts = "wait"`, it first waits for
+// conflicting records to be removed.
+func (c *Config) createRecord(
+	ctx context.Context,
+	d *schema.ResourceData,
+	domain string,
+	record gonjalla.Record,
+	resource string,
+) (gonjalla.Record, diag.Diagnostics) {
+	name := dnsrecord.NormalizeName(record.Name, domain)
+
+	diags := c.waitForCNAMEConflicts(
+		ctx, domain, record.Type, name, "", "create", resource,
+	)
+	if diags.HasError() {
+		return gonjalla.Record{}, diags
+	}
+
+	adopt := c.adoptExisting(d)
+
+	var records []gonjalla.Record
+	if adopt || c.OwnerID != "" {
+		var err error
+		records, err = c.listRecords(ctx, domain)
+		if err != nil {
+			return gonjalla.Record{}, append(
+				diags, diagFromAPIError(err, "create", resource)...,
+			)
+		}
+	}
+
+	if c.OwnerID != "" {
+		ownerDiags := c.checkOwnership(records, domain, record.Type, name, "create", resource)
+		if ownerDiags.HasError() {
+			return gonjalla.Record{}, append(diags, ownerDiags...)
+		}
+	}
+
+	var saved gonjalla.Record
+	if adopt {
+		var adoptDiags diag.Diagnostics
+		saved, adoptDiags = c.adoptRecord(ctx, records, domain, record, resource)
+		diags = append(diags, adoptDiags...)
+	}
+
+	if saved.ID == "" && !diags.HasError() {
+		var err error
+		saved, err = c.addRecord(ctx, domain, record)
+		if err != nil {
+			return saved, append(
+				diags, diagFromAPIError(err, "create", resource)...,
+			)
+		}
+	}
+
+	if c.OwnerID != "" && !diags.HasError() {
+		if err := c.claimOwnership(ctx, records, domain, record.Type, name); err != nil {
+			return saved, append(
+				diags, diagFromAPIError(err, "create", resource)...,
+			)
+		}
+	}
+
+	return saved, diags
+}
+
+// adoptRecord returns the record among `records` identical to `record`, if
+// any, editing its TTL if needed. Records whose marker names an owner other
+// than the provider's are never adopted, as they're managed elsewhere.
+func (c *Config) adoptRecord(
+	ctx context.Context,
+	records []gonjalla.Record,
+	domain string,
+	record gonjalla.Record,
+	resource string,
+) (gonjalla.Record, diag.Diagnostics) {
+	name := dnsrecord.NormalizeName(record.Name, domain)
+
+	for _, existing := range records {
+		if !dnsrecord.SameRecord(existing, record, domain) {
+			continue
+		}
+
+		if marker := findOwnerMarker(records, domain, record.Type, name); marker != nil {
+			owner, _ := parseOwnerMarker(marker.Content)
+			if owner != c.OwnerID {
+				return gonjalla.Record{}, diag.Diagnostics{
+					{
+						Severity: diag.Warning,
+						Summary: fmt.Sprintf(
+							"Existing record not adopted for %s", resource,
+						),
+						Detail: fmt.Sprintf(
+							"An identical %s record %s exists in domain %s, "+
+								"but its ownership marker %s names the owner "+
+								"%q, so a new record was added instead of "+
+								"adopting it.",
+							existing.Type, existing.ID, domain,
+							ownerMarkerName(record.Type, name), owner,
+						),
+					},
+				}
+			}
+		}
+
+		var diags diag.Diagnostics
+
+		if existing.TTL != record.TTL {
+			record.ID = existing.ID
+			if err := c.editRecord(ctx, domain, record); err != nil {
+				return gonjalla.Record{}, diagFromAPIError(err, "create", resource)
+			}
+
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("Changed ttl of adopted record for %s", resource),
+				Detail: fmt.Sprintf(
+					"The adopted %s record %s in domain %s had a ttl of %s, "+
+						"which was changed to %s.",
+					existing.Type, existing.ID, domain,
+					formatTTL(existing.TTL), formatTTL(record.TTL),
+				),
+			})
+			existing.TTL = record.TTL
+		}
+
+		tflog.Warn(ctx, "Adopted existing Njalla record", map[string]interface{}{
+			"njalla_domain":    domain,
+			"njalla_record_id": existing.ID,
+			"resource":         resource,
+		})
+
+		return existing, append(diag.Diagnostics{
+			{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("Adopted existing record for %s", resource),
+				Detail: fmt.Sprintf(
+					"An identical %s record %s already existed in domain %s, "+
+						"so it was adopted instead of adding a new one, as "+
+						"adopt_existing is enabled.",
+					existing.Type, existing.ID, domain,
+				),
+			},
+		}, diags...)
+	}
+
+	return gonjalla.Record{}, nil
+}
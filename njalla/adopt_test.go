@@ -0,0 +1,298 @@
+package njalla
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// testAdoptRecordA creates an A record `www` with the provider's
+// `adopt_existing`, as resources built by `schema.TestResourceDataRaw` have
+// no raw configuration to read their own from.
+func testAdoptRecordA(
+	t *testing.T, config *Config, ttl string,
+) (*schema.ResourceData, diag.Diagnostics) {
+	config.AdoptExisting = true
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     ttl,
+			"content": "1.1.1.1",
+		},
+	)
+
+	return d, resourceRecordACreate(context.Background(), d, config)
+}
+
+// testHasWarning reports whether `diags` has a warning whose summary starts
+// with `summary`.
+func testHasWarning(diags diag.Diagnostics, summary string) bool {
+	for _, d := range diags {
+		if d.Severity == diag.Warning && strings.HasPrefix(d.Summary, summary) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// testAdopts reports whether `adoptRecord` adopts `existing`, added to the
+// fake, for `record`.
+func testAdopts(t *testing.T, existing, record gonjalla.Record) bool {
+	config, server := newTestCRUDConfig(t)
+
+	added := server.AddRecord("testing.com", existing)
+	adopted, diags := config.adoptRecord(
+		context.Background(), []gonjalla.Record{added}, "testing.com", record,
+		"njalla_record_"+strings.ToLower(record.Type)+".test",
+	)
+	if diags.HasError() {
+		t.Fatalf("adoptRecord failed: %v", diags)
+	}
+
+	return adopted.ID == added.ID
+}
+
+func TestSameRecord(t *testing.T) {
+	priority := 10
+	otherPriority := 20
+
+	record := gonjalla.Record{
+		Type:     "MX",
+		Name:     "mail",
+		Content:  "mx.example.com",
+		TTL:      3600,
+		Priority: &priority,
+	}
+
+	cases := []struct {
+		existing gonjalla.Record
+		expected bool
+	}{
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail", Content: "mx.example.com",
+				TTL: 3600, Priority: &priority,
+			},
+			true,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail.testing.com.", Content: "MX.example.com.",
+				TTL: 10800, Priority: &priority,
+			},
+			true,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "mail", Content: "mx.example.com",
+				TTL: 3600, Priority: &otherPriority,
+			},
+			false,
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Name: "www", Content: "mx.example.com",
+				TTL: 3600, Priority: &priority,
+			},
+			false,
+		},
+		{
+			gonjalla.Record{
+				Type: "CNAME", Name: "mail", Content: "mx.example.com",
+				TTL: 3600,
+			},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		if same := testAdopts(t, c.existing, record); same != c.expected {
+			t.Errorf(
+				"adoptRecord(%+v) adopted: %t, expected %t",
+				c.existing, same, c.expected,
+			)
+		}
+	}
+}
+
+func TestEqualRecordContent(t *testing.T) {
+	cases := []struct {
+		recordType string
+		a          string
+		b          string
+		expected   bool
+	}{
+		{"A", "1.1.1.1", "1.1.1.1", true},
+		{"A", "1.1.1.1", "1.1.1.2", false},
+		{"AAAA", "2001:db8::1", "2001:0db8:0:0:0:0:0:1", true},
+		{"CNAME", "example.com", "EXAMPLE.com.", true},
+		{"TXT", "text", "\"text\"", true},
+		{"TXT", "text", "other", false},
+		{"CAA", "0 issue \"letsencrypt.org\"", "0 ISSUE letsencrypt.org", true},
+		{"TLSA", "3 1 1 ab", "3 1 1 cd", false},
+	}
+
+	for _, c := range cases {
+		existing := gonjalla.Record{
+			Type: c.recordType, Name: "www", Content: c.a, TTL: 3600,
+		}
+		record := gonjalla.Record{
+			Type: c.recordType, Name: "www", Content: c.b, TTL: 3600,
+		}
+		if equal := testAdopts(t, existing, record); equal != c.expected {
+			t.Errorf(
+				"adoptRecord(%s, %q, %q) adopted: %t, expected %t",
+				c.recordType, c.a, c.b, equal, c.expected,
+			)
+		}
+	}
+}
+
+func TestAdoptRecordChangedTTL(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+
+	existing := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+
+	d, diags := testAdoptRecordA(t, config, "10800")
+	if diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+
+	if d.Id() != existing.ID {
+		t.Fatalf("Expected record %s adopted, got %s", existing.ID, d.Id())
+	}
+	if !testHasWarning(diags, "Changed ttl of adopted record") {
+		t.Fatalf("Expected a warning about the changed ttl, got %v", diags)
+	}
+	if records := server.Records("testing.com"); records[0].TTL != 10800 {
+		t.Fatalf("Expected the ttl changed, got %v", records)
+	}
+}
+
+func TestAdoptRecordSameTTL(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+
+	_, diags := testAdoptRecordA(t, config, "3600")
+	if diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+
+	if !testHasWarning(diags, "Adopted existing record") {
+		t.Fatalf("Expected a warning about the adoption, got %v", diags)
+	}
+	if testHasWarning(diags, "Changed ttl of adopted record") {
+		t.Fatalf("Unexpected warning about the ttl: %v", diags)
+	}
+	if server.CallCount("edit-record") != 0 {
+		t.Fatal("Unexpected edit of the adopted record")
+	}
+}
+
+func TestAdoptRecordForeignMarker(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+
+	existing := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type:    "TXT",
+		Name:    "_njalla-owner-a.www",
+		Content: dnsrecord.FormatTXT(ownerMarkerText("team-b")),
+		TTL:     10800,
+	})
+
+	d, diags := testAdoptRecordA(t, config, "3600")
+	if diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+
+	if d.Id() == existing.ID {
+		t.Fatal("Adopted a record owned by another owner")
+	}
+	if !testHasWarning(diags, "Existing record not adopted") {
+		t.Fatalf("Expected a warning about the skipped record, got %v", diags)
+	}
+}
+
+func TestAdoptRecordSetForeignMarker(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	config.AdoptExisting = true
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type:    "TXT",
+		Name:    "_njalla-owner-a.www",
+		Content: dnsrecord.FormatTXT(ownerMarkerText("team-b")),
+		TTL:     10800,
+	})
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordSet().Schema, map[string]interface{}{
+			"domain": "testing.com",
+			"name":   "www",
+			"type":   "A",
+			"ttl":    "3600",
+			"values": []interface{}{
+				map[string]interface{}{"value": "1.1.1.2"},
+			},
+		},
+	)
+
+	diags := resourceRecordSetCreate(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Fatal("Expected adopting records owned by another owner to fail")
+	}
+	if len(server.Records("testing.com")) != 2 {
+		t.Fatalf("Unexpected changes %v", server.Records("testing.com"))
+	}
+}
+
+func TestUpdateOnlyAdoptExisting(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	config.OwnerID = "team-a"
+
+	existing := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 3600,
+	})
+	state := &terraform.InstanceState{
+		ID: existing.ID,
+		Attributes: map[string]string{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "3600",
+			"content": "1.1.1.1",
+		},
+	}
+	d := testResourceDataUpdate(t, resourceRecordA(), state, map[string]interface{}{
+		"domain":         "testing.com",
+		"name":           "www",
+		"ttl":            "3600",
+		"content":        "1.1.1.1",
+		"adopt_existing": true,
+	})
+
+	if diags := resourceRecordAUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Update failed: %v", diags)
+	}
+	if server.CallCount("edit-record") != 0 {
+		t.Fatal("Unexpected edit of the record")
+	}
+}
@@ -0,0 +1,108 @@
+package njalla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// errReadOnly is returned by any mutating API call attempted while the
+// provider is configured as read-only.
+var errReadOnly = errors.New("provider is configured as read-only")
+
+// Every wrapper sends the domain to the API in its ASCII form, so
+// internationalized domains can be configured in either form.
+
+// listRecords lists the records of a domain through the provider's client.
+func (c *Config) listRecords(
+	ctx context.Context, domain string,
+) ([]gonjalla.Record, error) {
+	return c.Client.ListRecords(ctx, dnsrecord.ToASCII(domain))
+}
+
+// addRecord adds a record through the provider's client.
+func (c *Config) addRecord(
+	ctx context.Context, domain string, record gonjalla.Record,
+) (gonjalla.Record, error) {
+	if err := c.checkMutation("add-record", domain); err != nil {
+		return gonjalla.Record{}, err
+	}
+
+	return c.Client.AddRecord(ctx, dnsrecord.ToASCII(domain), record)
+}
+
+// editRecord edits a record through the provider's client.
+func (c *Config) editRecord(
+	ctx context.Context, domain string, record gonjalla.Record,
+) error {
+	if err := c.checkMutation("edit-record", domain); err != nil {
+		return err
+	}
+
+	return c.Client.EditRecord(ctx, dnsrecord.ToASCII(domain), record)
+}
+
+// removeRecord removes a record through the provider's client.
+func (c *Config) removeRecord(
+	ctx context.Context, domain string, id string,
+) error {
+	if err := c.checkMutation("remove-record", domain); err != nil {
+		return err
+	}
+
+	return c.Client.RemoveRecord(ctx, dnsrecord.ToASCII(domain), id)
+}
+
+// checkMutation fails any API method that changes data while in read-only
+// mode. Resources are expected to have refused the operation already
+// through `checkWritable`; this is the last line of defence.
+func (c *Config) checkMutation(method string, domain string) error {
+	if !c.ReadOnly {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"Njalla API call %s for domain %s refused: %w",
+		method, domain, errReadOnly,
+	)
+}
+
+// diagFromAPIError converts an error returned by an API call into
+// diagnostics. Timeouts and cancellations get a diagnostic naming the
+// resource and operation that was interrupted, anything else is passed
+// through `diag.FromErr`.
+func diagFromAPIError(err error, operation string, resource string) diag.Diagnostics {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary: fmt.Sprintf(
+					"Timed out during %s of %s", operation, resource,
+				),
+				Detail: fmt.Sprintf(
+					"%s. Consider raising the %s value of this resource's "+
+						"timeouts block.",
+					err, operation,
+				),
+			},
+		}
+	case errors.Is(err, context.Canceled):
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary: fmt.Sprintf(
+					"Cancelled during %s of %s", operation, resource,
+				),
+				Detail: err.Error(),
+			},
+		}
+	}
+
+	return diag.FromErr(err)
+}
@@ -0,0 +1,90 @@
+package njalla
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+)
+
+// newTestConfig returns a provider config whose client sends every request
+// to a test server running the given handler.
+func newTestConfig(t *testing.T, handler http.Handler) *Config {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	return &Config{Client: client}
+}
+
+func TestDiagFromAPIErrorDeadline(t *testing.T) {
+	err := &api.Error{
+		Method:    "add-record",
+		Domain:    "testing.com",
+		RequestID: "1234",
+		Err:       context.DeadlineExceeded,
+	}
+
+	diags := diagFromAPIError(err, "create", "njalla_record_a")
+
+	expected := "Timed out during create of njalla_record_a"
+	if diags[0].Summary != expected {
+		t.Fatalf("Expected summary %q, got %q", expected, diags[0].Summary)
+	}
+}
+
+func TestDiagFromAPIErrorOther(t *testing.T) {
+	diags := diagFromAPIError(errors.New("boom"), "create", "njalla_record_a")
+
+	if diags[0].Summary != "boom" {
+		t.Fatalf("Unexpected summary %q", diags[0].Summary)
+	}
+}
+
+func TestResourceTimeoutDiagnostic(t *testing.T) {
+	config := newTestConfig(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "test",
+			"ttl":     "10800",
+			"content": "1.1.1.1",
+		},
+	)
+
+	start := time.Now()
+	diags := resourceRecordACreate(ctx, d, config)
+	if !diags.HasError() {
+		t.Fatal("Expected Create to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Create returned after %s, deadline was ignored", elapsed)
+	}
+
+	expected := "Timed out during create of njalla_record_a"
+	if diags[0].Summary != expected {
+		t.Fatalf("Expected summary %q, got %q", expected, diags[0].Summary)
+	}
+}
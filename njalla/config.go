@@ -1,6 +1,76 @@
 package njalla
 
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"golang.org/x/time/rate"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+)
+
 // Config is the metadata interface provider passed later on to resources
 type Config struct {
-	Token string
+	// Client is used for every Njalla API call made by resources.
+	Client   *api.Client
+	ReadOnly bool
+
+	// DefaultTTL is used by records without a `ttl`, in seconds.
+	DefaultTTL int
+	// TTLRounding is how TTLs Njalla doesn't accept are handled, either
+	// `none` or `nearest`.
+	TTLRounding string
+	// AdoptExisting makes Create adopt identical existing records, unless
+	// a resource sets its own `adopt_existing`.
+	AdoptExisting bool
+	// CNAMEConflicts is how records conflicting with a CNAME are handled,
+	// either `error` at plan time or `wait` for them to be removed.
+	CNAMEConflicts string
+	// OwnerID enables the ownership registry, naming the owner of the
+	// records this provider creates.
+	OwnerID string
+	// ClaimUnmarked makes the registry claim records without a marker
+	// instead of refusing to change them.
+	ClaimUnmarked bool
+
+	// ProtectedRecords are the records that can't be deleted, unless
+	// AllowProtectedDeletion is set.
+	ProtectedRecords       []recordPattern
+	AllowProtectedDeletion bool
+}
+
+// newLimiter returns a token bucket allowing `rps` requests per second with
+// bursts of up to `burst` requests. A non-positive `rps` disables limiting.
+func newLimiter(rps float64, burst int) *rate.Limiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return rate.NewLimiter(rate.Limit(rps), burst)
+}
+
+// checkWritable must be called at the very start of every Create, Update and
+// Delete function, before any API call is made. It returns an error
+// diagnostic if the provider was configured with `read_only`, and nil
+// otherwise.
+func (c *Config) checkWritable(operation string, resource string) diag.Diagnostics {
+	if !c.ReadOnly {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Error,
+			Summary:  "Njalla provider is in read-only mode",
+			Detail: fmt.Sprintf(
+				"Refusing to %s %s because the provider is configured with "+
+					"read_only = true (or NJALLA_READ_ONLY). Only reads and "+
+					"data sources are allowed.",
+				operation, resource,
+			),
+		},
+	}
 }
@@ -0,0 +1,82 @@
+package njalla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestCheckWritableReadOnly(t *testing.T) {
+	config := &Config{ReadOnly: true}
+
+	diags := config.checkWritable("create", "njalla_record_a")
+	if !diags.HasError() {
+		t.Fatal("Expected an error diagnostic")
+	}
+}
+
+func TestCheckWritableReadWrite(t *testing.T) {
+	config := &Config{}
+
+	if diags := config.checkWritable("create", "njalla_record_a"); diags != nil {
+		t.Fatalf("Unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestReadOnlyRefusesMutations(t *testing.T) {
+	config := newTestConfig(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Unexpected API request in read-only mode")
+		},
+	))
+	config.ReadOnly = true
+
+	ctx := context.Background()
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "test",
+			"ttl":     "10800",
+			"content": "1.1.1.1",
+		},
+	)
+	d.SetId("1234")
+
+	if diags := resourceRecordACreate(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Create to fail in read-only mode")
+	}
+	if diags := resourceRecordAUpdate(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Update to fail in read-only mode")
+	}
+	if diags := resourceRecordADelete(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Delete to fail in read-only mode")
+	}
+
+	err := config.removeRecord(ctx, "testing.com", "1234")
+	if !errors.Is(err, errReadOnly) {
+		t.Fatalf("Expected errReadOnly, got %v", err)
+	}
+}
+
+func TestNewLimiterDisabled(t *testing.T) {
+	if limiter := newLimiter(0, 5); limiter != nil {
+		t.Fatalf("Expected no limiter, got %v", limiter)
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	limiter := newLimiter(2.5, 3)
+	if limiter == nil {
+		t.Fatal("Expected a limiter")
+	}
+	if limiter.Limit() != 2.5 || limiter.Burst() != 3 {
+		t.Fatalf(
+			"Unexpected limiter %v requests/s, burst %d",
+			limiter.Limit(), limiter.Burst(),
+		)
+	}
+}
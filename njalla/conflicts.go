@@ -0,0 +1,191 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// A CNAME can't coexist with any other record at the same name, as defined in
+// RFC 1034 section 3.6.2. Njalla only reports such a conflict when the record
+// is added, which can leave an apply half done, so by default it's checked at
+// plan time against the records already in the zone.
+//
+// Records planned in the same run aren't in the zone yet, so a conflict
+// between two new resources is still only reported when applied. Records
+// destroyed in the same run still are, so replacing an A record with a CNAME
+// can't be planned. With the provider's `cname_conflicts = "wait"` the plan
+// isn't checked, and creating or renaming a record instead waits for the
+// conflicting records to be removed, like the A record destroyed alongside.
+
+const (
+	cnameConflictsError = "error"
+	cnameConflictsWait  = "wait"
+)
+
+// cnameConflictInterval is how often the zone is listed while waiting for
+// conflicting records to be removed.
+var cnameConflictInterval = 5 * time.Second
+
+// validateCNAMEConflicts returns a `CustomizeDiff` failing the plan if a
+// record of type `recordType` would share its name with a CNAME, or, for a
+// CNAME, with any other record. The zone is only listed when the record is
+// created or its name changes, so unchanged records don't cost an API call
+// on every plan.
+func validateCNAMEConflicts(recordType string) schema.CustomizeDiffFunc {
+	return func(
+		ctx context.Context, d *schema.ResourceDiff, m interface{},
+	) error {
+		config, ok := m.(*Config)
+		if !ok || config == nil || config.Client == nil {
+			return nil
+		}
+
+		if config.CNAMEConflicts == cnameConflictsWait {
+			return nil
+		}
+
+		if !d.NewValueKnown("name") || !d.NewValueKnown("domain") {
+			return nil
+		}
+
+		if d.Id() != "" && !d.HasChange("name") && !d.HasChange("domain") {
+			return nil
+		}
+
+		domain := d.Get("domain").(string)
+		name := dnsrecord.NormalizeName(d.Get("name").(string), domain)
+
+		records, err := config.listRecords(ctx, domain)
+		if err != nil {
+			return fmt.Errorf(
+				"Checking for CNAME conflicts in domain %s failed: %s",
+				domain, err,
+			)
+		}
+
+		if conflict := findCNAMEConflict(
+			records, domain, recordType, name, d.Id(),
+		); conflict != nil {
+			return cnameConflictError(domain, recordType, name, *conflict)
+		}
+
+		return nil
+	}
+}
+
+// findCNAMEConflict returns the record among `records`, other than the one
+// with ID `id`, that a record of `recordType` at `name` would conflict with,
+// or nil if there's none.
+func findCNAMEConflict(
+	records []gonjalla.Record,
+	domain string,
+	recordType string,
+	name string,
+	id string,
+) *gonjalla.Record {
+	for i, record := range records {
+		if id != "" && record.ID == id {
+			continue
+		}
+
+		if !strings.EqualFold(
+			dnsrecord.NormalizeName(record.Name, domain), name,
+		) {
+			continue
+		}
+
+		if recordType != "CNAME" && record.Type != "CNAME" {
+			continue
+		}
+
+		return &records[i]
+	}
+
+	return nil
+}
+
+func cnameConflictError(
+	domain string, recordType string, name string, conflict gonjalla.Record,
+) error {
+	return fmt.Errorf(
+		"%s record %s in domain %s conflicts with the existing %s "+
+			"record %s. A CNAME can't share its name with any other "+
+			"record. Check RFC 1034 section 3.6.2",
+		recordType, name, domain, conflict.Type, conflict.ID,
+	)
+}
+
+// waitForCNAMEConflicts waits until a record of `recordType` at `name`
+// doesn't conflict with any record other than the one with ID `id`, when
+// the provider is configured with `cname_conflicts = "wait"`. It gives up
+// once `ctx` is done. A warning diagnostic is returned if it had to wait.
+func (c *Config) waitForCNAMEConflicts(
+	ctx context.Context,
+	domain string,
+	recordType string,
+	name string,
+	id string,
+	operation string,
+	resource string,
+) diag.Diagnostics {
+	if c.CNAMEConflicts != cnameConflictsWait {
+		return nil
+	}
+
+	var waited *gonjalla.Record
+	for {
+		records, err := c.listRecords(ctx, domain)
+		if err != nil {
+			return diagFromAPIError(err, operation, resource)
+		}
+
+		conflict := findCNAMEConflict(records, domain, recordType, name, id)
+		if conflict == nil {
+			break
+		}
+		waited = conflict
+
+		select {
+		case <-ctx.Done():
+			return diag.Diagnostics{
+				{
+					Severity: diag.Error,
+					Summary: fmt.Sprintf(
+						"Conflicting record never removed for %s", resource,
+					),
+					Detail: fmt.Sprintf(
+						"%s. It was still there when giving up: %s.",
+						cnameConflictError(domain, recordType, name, *conflict),
+						ctx.Err(),
+					),
+				},
+			}
+		case <-time.After(cnameConflictInterval):
+		}
+	}
+
+	if waited == nil {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Waited for a conflicting record for %s", resource),
+			Detail: fmt.Sprintf(
+				"The %s record %s at %s in domain %s conflicted with this %s "+
+					"record, so the %s waited for it to be removed, as "+
+					"cname_conflicts is \"wait\".",
+				waited.Type, waited.ID, name, domain, recordType, operation,
+			),
+		},
+	}
+}
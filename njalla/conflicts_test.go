@@ -0,0 +1,82 @@
+package njalla
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+
+	"github.com/Sighery/gonjalla"
+)
+
+func TestWaitForCNAMEConflicts(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	config.CNAMEConflicts = cnameConflictsWait
+
+	interval := cnameConflictInterval
+	cnameConflictInterval = 10 * time.Millisecond
+	defer func() { cnameConflictInterval = interval }()
+
+	conflict := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 10800,
+	})
+	time.AfterFunc(100*time.Millisecond, func() {
+		config.Client.RemoveRecord(context.Background(), "testing.com", conflict.ID)
+	})
+
+	diags := config.waitForCNAMEConflicts(
+		context.Background(), "testing.com", "CNAME", "www", "",
+		"create", "njalla_record_cname",
+	)
+	if diags.HasError() || len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Fatalf("Expected a single warning, got %v", diags)
+	}
+}
+
+func TestWaitForCNAMEConflictsTimeout(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	config.CNAMEConflicts = cnameConflictsWait
+
+	interval := cnameConflictInterval
+	cnameConflictInterval = 10 * time.Millisecond
+	defer func() { cnameConflictInterval = interval }()
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "CNAME", Name: "www", Content: "other.testing.com.", TTL: 10800,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	diags := config.waitForCNAMEConflicts(
+		ctx, "testing.com", "A", "www", "", "create", "njalla_record_a",
+	)
+	if !diags.HasError() {
+		t.Fatal("Expected the wait to time out")
+	}
+	if !strings.Contains(diags[0].Detail, "context deadline exceeded") {
+		t.Fatalf("Expected the context error reported, got %q", diags[0].Detail)
+	}
+}
+
+func TestWaitForCNAMEConflictsDisabled(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "CNAME", Name: "www", Content: "other.testing.com.", TTL: 10800,
+	})
+
+	// By default conflicts are reported at plan time instead.
+	diags := config.waitForCNAMEConflicts(
+		context.Background(), "testing.com", "A", "www", "",
+		"create", "njalla_record_a",
+	)
+	if diags != nil {
+		t.Fatalf("Unexpected diagnostics %v", diags)
+	}
+	if server.CallCount("list-records") != 0 {
+		t.Fatal("Unexpected API call")
+	}
+}
@@ -0,0 +1,207 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+func dataSourceZoneDrift() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceZoneDriftRead,
+
+		Schema: map[string]*schema.Schema{
+			"domain": {
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Domain to look for unmanaged records in.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
+			},
+			"managed_ids": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "IDs of the records and record sets managed in configuration.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"fail_on_unmanaged": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Fail if the domain has any unmanaged record.",
+			},
+			"unmanaged_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "IDs of the unmanaged records.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"unmanaged_records": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Records of the domain not in managed_ids.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"content": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"ttl": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"priority": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(defaultRecordTimeout),
+		},
+	}
+}
+
+func dataSourceZoneDriftRead(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+
+	domain := d.Get("domain").(string)
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return diagFromAPIError(err, "read", "njalla_zone_drift")
+	}
+
+	managed := map[string]bool{}
+	for _, id := range d.Get("managed_ids").(*schema.Set).List() {
+		managed[id.(string)] = true
+	}
+
+	unmanaged := unmanagedRecords(records, domain, managed, config.OwnerID)
+
+	ids := make([]string, 0, len(unmanaged))
+	flattened := make([]interface{}, 0, len(unmanaged))
+	for _, record := range unmanaged {
+		ids = append(ids, record.ID)
+		flattened = append(flattened, flattenRecord(record))
+	}
+
+	d.SetId(dnsrecord.ToASCII(domain))
+	d.Set("unmanaged_ids", ids)
+	d.Set("unmanaged_records", flattened)
+
+	if len(unmanaged) == 0 || !d.Get("fail_on_unmanaged").(bool) {
+		return nil
+	}
+
+	lines := make([]string, 0, len(unmanaged))
+	for _, record := range unmanaged {
+		lines = append(lines, fmt.Sprintf(
+			"  %s %s %s %q", record.ID, record.Type, record.Name, record.Content,
+		))
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Error,
+			Summary: fmt.Sprintf(
+				"Found %d unmanaged records in domain %s",
+				len(unmanaged), domain,
+			),
+			Detail: fmt.Sprintf(
+				"These records aren't in managed_ids, and fail_on_unmanaged "+
+					"is set:\n%s",
+				strings.Join(lines, "\n"),
+			),
+		},
+	}
+}
+
+// unmanagedRecords returns the records of `domain` whose ID isn't in
+// `managed`. IDs of record sets, like `example.com:www:A`, manage every
+// record of their type at their name. The ownership markers of `owner`, if
+// any, are left out, as they're managed by the provider along with their
+// records.
+func unmanagedRecords(
+	records []gonjalla.Record,
+	domain string,
+	managed map[string]bool,
+	owner string,
+) []gonjalla.Record {
+	sets := map[string]bool{}
+	for id := range managed {
+		if !strings.Contains(id, ":") {
+			continue
+		}
+
+		setDomain, name, recordType, err := parseRecordSetID(id)
+		if err != nil {
+			continue
+		}
+		sets[strings.ToLower(recordSetID(setDomain, name, recordType))] = true
+	}
+
+	var unmanaged []gonjalla.Record
+
+	for _, record := range records {
+		if managed[record.ID] {
+			continue
+		}
+
+		name := dnsrecord.NormalizeName(record.Name, domain)
+		if sets[strings.ToLower(recordSetID(domain, name, record.Type))] {
+			continue
+		}
+
+		if owner != "" && record.Type == "TXT" {
+			if markerOwner, ok := parseOwnerMarker(record.Content); ok &&
+				markerOwner == owner {
+				continue
+			}
+		}
+
+		unmanaged = append(unmanaged, record)
+	}
+
+	return unmanaged
+}
+
+// flattenRecord returns a record as a `unmanaged_records` element.
+func flattenRecord(record gonjalla.Record) map[string]interface{} {
+	flattened := map[string]interface{}{
+		"id":      record.ID,
+		"name":    record.Name,
+		"type":    record.Type,
+		"content": record.Content,
+		"ttl":     record.TTL,
+	}
+
+	if record.Priority != nil {
+		flattened["priority"] = *record.Priority
+	}
+
+	return flattened
+}
@@ -0,0 +1,178 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+func TestAccZoneDrift_Unmanaged(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	var unmanagedID string
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				// Adds a record by hand, as if through Njalla's panel.
+				PreConfig: func() {
+					client := testAccAPIClient()
+					saved, err := client.AddRecord(
+						context.Background(), domain, gonjalla.Record{
+							Type:    "TXT",
+							Name:    "testacc1-drift-unmanaged-name",
+							Content: "testacc1-drift-unmanaged-content",
+							TTL:     10800,
+						},
+					)
+					if err != nil {
+						t.Fatalf("Adding the unmanaged record failed: %s", err)
+					}
+					unmanagedID = saved.ID
+
+					t.Cleanup(func() {
+						client.RemoveRecord(context.Background(), domain, saved.ID)
+					})
+				},
+				Config: testAccCheckZoneDrift(false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckTypeSetElemNestedAttrs(
+						"data.njalla_zone_drift.test",
+						"unmanaged_records.*",
+						map[string]string{
+							"name":    "testacc1-drift-unmanaged-name",
+							"type":    "TXT",
+							"content": "testacc1-drift-unmanaged-content",
+						},
+					),
+					testAccCheckZoneDriftIDs(&unmanagedID),
+				),
+			},
+			{
+				Config: testAccCheckZoneDrift(true),
+				ExpectError: regexp.MustCompile(
+					"Found [0-9]+ unmanaged records in domain",
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckZoneDriftIDs checks the hand-made record is reported as
+// unmanaged, and the managed record isn't.
+func testAccCheckZoneDriftIDs(unmanagedID *string) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		managed := state.RootModule().Resources["njalla_record_a.test_drift"]
+		drift := state.RootModule().Resources["data.njalla_zone_drift.test"]
+		if managed == nil || drift == nil {
+			return fmt.Errorf("Not found: njalla_record_a.test_drift or the data source")
+		}
+
+		found := false
+		for key, value := range drift.Primary.Attributes {
+			if !regexp.MustCompile(`^unmanaged_ids\.[0-9]+$`).MatchString(key) {
+				continue
+			}
+
+			switch value {
+			case managed.Primary.ID:
+				return fmt.Errorf("Managed record %s reported as unmanaged", value)
+			case *unmanagedID:
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Unmanaged record %s not reported", *unmanagedID)
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckZoneDrift(failOnUnmanaged bool) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_drift {
+  domain = %[1]q
+  name = "testacc1-drift-managed-name"
+  ttl = 10800
+  content = "1.1.1.1"
+}
+
+data njalla_zone_drift test {
+  domain = %[1]q
+  managed_ids = [njalla_record_a.test_drift.id]
+  fail_on_unmanaged = %[2]t
+}
+`, domain, failOnUnmanaged)
+}
+
+func TestUnmanagedRecords(t *testing.T) {
+	records := []gonjalla.Record{
+		{ID: "1", Type: "A", Name: "www", Content: "1.1.1.1"},
+		{ID: "2", Type: "A", Name: "manual", Content: "1.1.1.2"},
+		{
+			ID:      "3",
+			Type:    "TXT",
+			Name:    "_njalla-owner-a.www",
+			Content: dnsrecord.FormatTXT(ownerMarkerText("team-a")),
+		},
+		{
+			ID:      "4",
+			Type:    "TXT",
+			Name:    "_njalla-owner-a.other",
+			Content: dnsrecord.FormatTXT(ownerMarkerText("team-b")),
+		},
+	}
+
+	unmanaged := unmanagedRecords(
+		records, "testing.com", map[string]bool{"1": true}, "team-a",
+	)
+
+	var ids []string
+	for _, record := range unmanaged {
+		ids = append(ids, record.ID)
+	}
+
+	if fmt.Sprint(ids) != "[2 4]" {
+		t.Fatalf("Expected records 2 and 4 unmanaged, got %v", ids)
+	}
+}
+
+func TestUnmanagedRecordsSetIDs(t *testing.T) {
+	records := []gonjalla.Record{
+		{ID: "1", Type: "NS", Name: "@", Content: "ns1.testing.com."},
+		{ID: "2", Type: "NS", Name: "testing.com", Content: "ns2.testing.com."},
+		{ID: "3", Type: "A", Name: "WWW", Content: "1.1.1.1"},
+		{ID: "4", Type: "A", Name: "www", Content: "1.1.1.2"},
+		{ID: "5", Type: "AAAA", Name: "www", Content: "2001:db8::1"},
+		{ID: "6", Type: "A", Name: "manual", Content: "1.1.1.3"},
+	}
+
+	managed := map[string]bool{
+		"testing.com:@:NS":     true,
+		"Testing.com:www:a":    true,
+		"example.com:manual:A": true,
+	}
+	unmanaged := unmanagedRecords(records, "testing.com", managed, "")
+
+	var ids []string
+	for _, record := range unmanaged {
+		ids = append(ids, record.ID)
+	}
+
+	if fmt.Sprint(ids) != "[5 6]" {
+		t.Fatalf("Expected records 5 and 6 unmanaged, got %v", ids)
+	}
+}
@@ -0,0 +1,174 @@
+package njalla
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// isDecimal reports whether s is a non-empty string of ASCII digits whose
+// value is at most max.
+func isDecimal(s string, max int) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	return err == nil && n <= max
+}
+
+func FuzzValidateTLSAContent(f *testing.F) {
+	for _, seed := range []string{
+		"3 0 0 30820307308201efa00302010202",
+		"0 0 1 bdf6d7b8435e0120320d92391826805bb9ab35ee23987682f47b4abaf2931d69",
+		"1 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"testacc6-tlsa-invalidcontent-content",
+		"test 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"999 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"0 test 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"0 999 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"0 1 test a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+		"0 1 999 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		_, errs := validateTLSAContent(content, "content")
+		if len(errs) > 0 {
+			return
+		}
+
+		fields := strings.Split(content, " ")
+		if len(fields) != 4 {
+			t.Fatalf("Accepted %q with %d fields", content, len(fields))
+		}
+		for _, field := range fields[:3] {
+			if !isDecimal(field, 255) {
+				t.Fatalf("Accepted %q with field %q", content, field)
+			}
+		}
+		if fields[3] == "" {
+			t.Fatalf("Accepted %q without association data", content)
+		}
+		if _, err := hex.DecodeString(fields[3]); err != nil {
+			t.Fatalf("Accepted %q with non hexadecimal data", content)
+		}
+	})
+}
+
+func FuzzValidateNAPTRContent(f *testing.F) {
+	for _, seed := range []string{
+		`100 10 "" "" "/urn:cid:.+@([^\.]+\.)(.*)$/\2/i" .`,
+		`100 50 "S" "z3950+I2L+I2C" "" _z3950._tcp.gatech.edu.`,
+		`100 50 "S" "rcds+I2C" "" _rcds._udp.gatech.edu.`,
+		`100 50 "S" "http+I2L+I2C+I2R" "" _http._tcp.gatech.edu.`,
+		"testacc6-naptr-invalidcontent-content",
+		`test 50 "S" "http+I2L+I2C+I2R" "" _http._tcp.gatech.edu.`,
+		`100 test "S" "http+I2L+I2C+I2R" "" _http._tcp.gatech.edu.`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		_, errs := validateNAPTRContent(content, "content")
+		if len(errs) > 0 {
+			return
+		}
+
+		fields := strings.Fields(content)
+		for _, field := range fields[:2] {
+			if !isDecimal(field, 65535) {
+				t.Fatalf("Accepted %q with field %q", content, field)
+			}
+		}
+
+		parsed, err := dnsrecord.ParseNAPTR(content)
+		if err != nil {
+			t.Fatalf("Accepted %q but failed to parse it: %s", content, err)
+		}
+		reparsed, err := dnsrecord.ParseNAPTR(parsed.String())
+		if err != nil || reparsed != parsed {
+			t.Fatalf("%q didn't round trip: %+v, %v", content, reparsed, err)
+		}
+	})
+}
+
+func FuzzValidateCAAContent(f *testing.F) {
+	for _, seed := range []string{
+		`0 issue "letsencrypt.org"`,
+		`0 iodef "mailto:security@example.com"`,
+		`128 issuewild ";"`,
+		`0 issue "ca.example.net; accounturi=https://ca.example.net/acct/1"`,
+		"testacc6-caa-invalidcontent-content",
+		`999 issue "letsencrypt.org"`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		_, errs := validateCAAContent(content, "content")
+		if len(errs) > 0 {
+			return
+		}
+
+		fields := strings.Fields(content)
+		if len(fields) < 3 {
+			t.Fatalf("Accepted %q with %d fields", content, len(fields))
+		}
+		if !isDecimal(fields[0], 255) {
+			t.Fatalf("Accepted %q with flag %q", content, fields[0])
+		}
+		switch strings.ToLower(fields[1]) {
+		case "issue", "issuewild", "iodef", "issuemail":
+		default:
+			t.Fatalf("Accepted %q with tag %q", content, fields[1])
+		}
+
+		parsed, err := dnsrecord.ParseCAA(content)
+		if err != nil {
+			t.Fatalf("Accepted %q but failed to parse it: %s", content, err)
+		}
+		reparsed, err := dnsrecord.ParseCAA(parsed.String())
+		if err != nil || reparsed != parsed {
+			t.Fatalf("%q didn't round trip: %+v, %v", content, reparsed, err)
+		}
+	})
+}
+
+func FuzzParseImportID(f *testing.F) {
+	for _, seed := range []string{
+		"testing.com:1234",
+		"testing.com:",
+		"testing.com",
+		":1234",
+		"testing.com:12:34",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, id string) {
+		domain, recordID, err := parseImportID(id)
+		if err != nil {
+			return
+		}
+
+		if domain == "" || recordID == "" {
+			t.Fatalf("Accepted %q with an empty part", id)
+		}
+		if strings.Contains(domain, ":") {
+			t.Fatalf("Accepted %q with domain %q", id, domain)
+		}
+		if domain+":"+recordID != id {
+			t.Fatalf("Parsing %q isn't lossless", id)
+		}
+	})
+}
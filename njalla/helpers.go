@@ -2,7 +2,15 @@ package njalla
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
 // parseImportID will parse a given resource ID when importing with the
@@ -19,3 +27,152 @@ func parseImportID(id string) (string, string, error) {
 
 	return parts[0], parts[1], nil
 }
+
+// validateHostname returns a `schema.SchemaValidateFunc` checking a record's
+// content is a hostname, as checked by `dnsrecord.ValidateHostname`. A
+// warning is returned for single label names, which are likely meant as
+// relative to the domain.
+func validateHostname(allowUnderscore bool) schema.SchemaValidateFunc {
+	return func(val interface{}, key string) (warns []string, errs []error) {
+		v := val.(string)
+		name := strings.TrimSuffix(v, ".")
+
+		if err := dnsrecord.ValidateHostname(key, v, allowUnderscore); err != nil {
+			errs = append(errs, err)
+			return
+		}
+
+		if !strings.Contains(dnsrecord.ToASCII(name), ".") {
+			warns = append(warns, fmt.Sprintf(
+				"%s %s is a single label, which is likely meant as a name "+
+					"relative to the domain. Use the fully-qualified name, "+
+					"like %s.example.com., to make sure it points to the "+
+					"intended host",
+				key, v, name,
+			))
+		}
+
+		return
+	}
+}
+
+// suppressEquivalentHostname ignores differences between hostnames with and
+// without a trailing dot, in their case, which is irrelevant in DNS, and
+// between the Unicode and ASCII forms of internationalized names.
+func suppressEquivalentHostname(
+	k, old, new string, d *schema.ResourceData,
+) bool {
+	return dnsrecord.EqualNames(old, new)
+}
+
+// hostnameContent returns the `content` of a record pointing to a hostname,
+// ready to be sent to the API. Internationalized names are sent in their
+// ASCII form.
+func hostnameContent(d *schema.ResourceData) string {
+	return dnsrecord.ToASCII(d.Get("content").(string))
+}
+
+// setHostnameContent sets `content` from the content of a record returned by
+// the API, keeping the current value if it's an equivalent hostname.
+func setHostnameContent(d *schema.ResourceData, content string) {
+	if dnsrecord.EqualNames(d.Get("content").(string), content) {
+		return
+	}
+
+	d.Set("content", content)
+}
+
+// validateContent returns a `schema.SchemaValidateFunc` checking a record's
+// content with `dnsrecord.Validate`, the way the records of `njalla_record_set`
+// are checked.
+func validateContent(recordType string) schema.SchemaValidateFunc {
+	return func(val interface{}, key string) (warns []string, errs []error) {
+		if err := dnsrecord.Validate(key, recordType, val.(string)); err != nil {
+			errs = append(errs, err)
+		}
+
+		return
+	}
+}
+
+// settingsAttributes are the arguments of record resources that only
+// configure how the provider applies changes, like `wait_for_propagation`
+// or `adopt_existing`. They aren't part of the record, so changing only them
+// needs no API call.
+var settingsAttributes = []string{"wait_for_propagation", "adopt_existing"}
+
+// onlySettingsChanged reports whether an update only changes
+// `settingsAttributes`.
+func onlySettingsChanged(d *schema.ResourceData) bool {
+	return !d.HasChangesExcept(settingsAttributes...)
+}
+
+// stringValidator adapts a function validating a single string into a
+// `schema.SchemaValidateFunc`.
+func stringValidator(validate func(string) error) schema.SchemaValidateFunc {
+	return func(val interface{}, key string) (warns []string, errs []error) {
+		if err := validate(val.(string)); err != nil {
+			errs = append(errs, err)
+		}
+
+		return
+	}
+}
+
+// rawConfigString returns a string attribute from the raw configuration, or
+// an empty string if it isn't set.
+func rawConfigString(raw cty.Value, key string) string {
+	value := raw.GetAttr(key)
+	if value.IsNull() || !value.IsKnown() {
+		return ""
+	}
+
+	return value.AsString()
+}
+
+// rawConfigInt returns a number attribute from the raw configuration, or 0
+// if it isn't set.
+func rawConfigInt(raw cty.Value, key string) int {
+	value := raw.GetAttr(key)
+	if value.IsNull() || !value.IsKnown() {
+		return 0
+	}
+
+	i, _ := value.AsBigFloat().Int64()
+	return int(i)
+}
+
+// envBoolDefaultFunc is like `schema.EnvDefaultFunc` for boolean arguments,
+// parsing the environment variable with `strconv.ParseBool` so values like
+// `1` or `TRUE` work as expected.
+func envBoolDefaultFunc(key string, dv bool) schema.SchemaDefaultFunc {
+	return func() (interface{}, error) {
+		v := os.Getenv(key)
+		if v == "" {
+			return dv, nil
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid boolean value for %s: %q", key, v,
+			)
+		}
+
+		return b, nil
+	}
+}
+
+// defaultRecordTimeout applies to every operation of a record resource
+// unless overridden through its `timeouts` block.
+const defaultRecordTimeout = 5 * time.Minute
+
+// recordTimeouts returns the `Timeouts` shared by every record resource.
+func recordTimeouts() *schema.ResourceTimeout {
+	return &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(defaultRecordTimeout),
+		Read:   schema.DefaultTimeout(defaultRecordTimeout),
+		Update: schema.DefaultTimeout(defaultRecordTimeout),
+		Delete: schema.DefaultTimeout(defaultRecordTimeout),
+	}
+}
@@ -2,6 +2,7 @@ package njalla
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +50,128 @@ func TestParseImportIDMissingIDWithoutColon(t *testing.T) {
 		t.Fatal("Unexpected success")
 	}
 }
+
+func TestEnvBoolDefaultFunc(t *testing.T) {
+	t.Setenv("NJALLA_TEST_BOOL", "1")
+
+	v, err := envBoolDefaultFunc("NJALLA_TEST_BOOL", false)()
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if v != true {
+		t.Fatalf("Expected true, got %v", v)
+	}
+}
+
+func TestEnvBoolDefaultFuncUnset(t *testing.T) {
+	v, err := envBoolDefaultFunc("NJALLA_TEST_BOOL_UNSET", true)()
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if v != true {
+		t.Fatalf("Expected default true, got %v", v)
+	}
+}
+
+func TestEnvBoolDefaultFuncInvalid(t *testing.T) {
+	t.Setenv("NJALLA_TEST_BOOL", "maybe")
+
+	_, err := envBoolDefaultFunc("NJALLA_TEST_BOOL", false)()
+	if err == nil {
+		t.Fatal("Unexpected success")
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	valid := []string{
+		"mail.example.com",
+		"mail.example.com.",
+		"xn--bcher-kva.example",
+		"bücher.example",
+		"a-b.example.com",
+		"testacc1-mx-create-content",
+	}
+
+	for _, hostname := range valid {
+		_, errs := validateHostname(false)(hostname, "content")
+		if len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %q: %v", hostname, errs)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"1.2.3.4",
+		"2001:db8::1",
+		"https://example.com",
+		"example.com/path",
+		"-mail.example.com",
+		"mail..example.com",
+		"mail example.com",
+		"_sip.example.com",
+		"example.123",
+		"xn--a.example",
+		strings.Repeat("a", 64) + ".example.com",
+	}
+
+	for _, hostname := range invalid {
+		_, errs := validateHostname(false)(hostname, "content")
+		if len(errs) == 0 {
+			t.Fatalf("Unexpected success for %q", hostname)
+		}
+	}
+
+	_, errs := validateHostname(true)("s1._domainkey.example.com", "content")
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors with underscores allowed: %v", errs)
+	}
+}
+
+func TestValidateContentAddresses(t *testing.T) {
+	cases := map[string][]string{
+		"A":    {"2001:db8::1", "::ffff:1.2.3.4"},
+		"AAAA": {"1.2.3.4", "::ffff:1.2.3.4"},
+	}
+
+	for recordType, contents := range cases {
+		validate := Provider().ResourcesMap["njalla_record_"+strings.ToLower(recordType)].
+			Schema["content"].ValidateFunc
+		for _, content := range contents {
+			if _, errs := validate(content, "content"); len(errs) == 0 {
+				t.Fatalf("Unexpected success for %s %q", recordType, content)
+			}
+		}
+	}
+}
+
+func TestValidateHostnameWarnings(t *testing.T) {
+	warns, _ := validateHostname(false)("mail", "content")
+	if len(warns) != 1 {
+		t.Fatalf("Expected a warning for a single label, got %v", warns)
+	}
+
+	warns, _ = validateHostname(false)("mail.example.com.", "content")
+	if len(warns) != 0 {
+		t.Fatalf("Unexpected warnings for a fully-qualified name: %v", warns)
+	}
+}
+
+func TestSuppressEquivalentHostname(t *testing.T) {
+	if !suppressEquivalentHostname(
+		"content", "mail.example.com.", "Mail.example.com", nil,
+	) {
+		t.Fatal("Expected trailing dot and case differences to be suppressed")
+	}
+
+	if !suppressEquivalentHostname(
+		"content", "mail.bücher.example", "mail.xn--bcher-kva.example.", nil,
+	) {
+		t.Fatal("Expected Unicode and ASCII forms to be suppressed")
+	}
+
+	if suppressEquivalentHostname(
+		"content", "mail.example.com", "mx.example.com", nil,
+	) {
+		t.Fatal("Expected different hostnames to not be suppressed")
+	}
+}
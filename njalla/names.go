@@ -0,0 +1,187 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// Record names can be given in several equivalent ways. For the domain
+// `example.com`, the apex can be `@`, an empty string, `example.com` or
+// `example.com.`, and `www` can also be `www.example.com` or
+// `www.example.com.`. Names are always sent to the API relative to the
+// domain, with `@` for the apex, and compared in that canonical form.
+//
+// Internationalized names can be given in their Unicode form, like
+// `bücher.example`, or their ASCII form, like `xn--bcher-kva.example`. They
+// are always sent to the API in their ASCII form.
+
+// validateDomain checks a domain is a valid domain name, in its Unicode or
+// ASCII form.
+func validateDomain(domain string) error {
+	ascii, err := dnsrecord.Profile.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil || !dnsrecord.IsDomainName(ascii) {
+		return fmt.Errorf(
+			"expected domain to be a valid domain name, got: %s", domain,
+		)
+	}
+
+	return nil
+}
+
+// recordName returns the canonical name of the record described by `d`,
+// ready to be sent to the API.
+func recordName(d *schema.ResourceData) string {
+	return dnsrecord.NormalizeName(
+		d.Get("name").(string), d.Get("domain").(string),
+	)
+}
+
+// setRecordName sets `name` from the name of a record returned by the API.
+// The current value is kept if it's equivalent, so the way the name is
+// written in the configuration doesn't show up as a change, otherwise the
+// canonical form is used.
+func setRecordName(d *schema.ResourceData, domain string, name string) {
+	canonical := dnsrecord.NormalizeName(name, domain)
+	current := d.Get("name").(string)
+
+	if current != "" &&
+		strings.EqualFold(dnsrecord.NormalizeName(current, domain), canonical) {
+		return
+	}
+
+	d.Set("name", canonical)
+}
+
+// suppressEquivalentRecordName ignores differences between names with the
+// same canonical form, in any case.
+func suppressEquivalentRecordName(
+	k, old, new string, d *schema.ResourceData,
+) bool {
+	domain := d.Get("domain").(string)
+
+	return strings.EqualFold(
+		dnsrecord.NormalizeName(old, domain), dnsrecord.NormalizeName(new, domain),
+	)
+}
+
+// validateRecordName checks the labels of a record name, in its ASCII form:
+// each between 1 and 63 characters of letters, digits, hyphens and
+// underscores, without leading or trailing hyphens. The leftmost label may be
+// a `*` wildcard. `@` and an empty name stand for the apex.
+func validateRecordName(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	name := strings.TrimSuffix(val.(string), ".")
+	if name == "" || name == "@" {
+		return
+	}
+
+	if !dnsrecord.IsASCII(name) || strings.Contains(strings.ToLower(name), "xn--") {
+		ascii, err := dnsrecord.Profile.ToASCII(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"expected %s to be a valid internationalized name, got: %s. "+
+					"%s",
+				key, val, err,
+			))
+			return
+		}
+		name = ascii
+	}
+
+	if len(name) > 253 {
+		errs = append(errs, fmt.Errorf(
+			"expected %s to be at most 253 characters, got: %d. Check RFC "+
+				"1035 section 2.3.4",
+			key, len(name),
+		))
+		return
+	}
+
+	for i, label := range strings.Split(name, ".") {
+		if label == "*" && i == 0 {
+			continue
+		}
+
+		if err := validateRecordNameLabel(label); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"expected %s to be a valid record name, got: %s. %s",
+				key, val, err,
+			))
+			return
+		}
+	}
+
+	return
+}
+
+// validateRecordNameLabel checks a single label of a record name.
+func validateRecordNameLabel(label string) error {
+	if len(label) == 0 || len(label) > 63 {
+		return fmt.Errorf(
+			"Labels must be between 1 and 63 characters, got %q", label,
+		)
+	}
+
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf(
+			"Labels can't start or end with a hyphen, got %q", label,
+		)
+	}
+
+	for _, c := range label {
+		switch {
+		case 'a' <= c && c <= 'z':
+		case 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9':
+		case c == '-' || c == '_':
+		case c == '*':
+			return fmt.Errorf(
+				"A wildcard must be the whole leftmost label, got %q", label,
+			)
+		default:
+			return fmt.Errorf(
+				"Labels can only contain letters, digits, hyphens and "+
+					"underscores, got %q",
+				label,
+			)
+		}
+	}
+
+	return nil
+}
+
+// validateRecordNameLength is a `CustomizeDiff` checking the fully-qualified
+// name of a record, made of its name and domain, fits in 253 characters.
+// This can't be checked by `validateRecordName`, as it doesn't know the
+// domain.
+func validateRecordNameLength(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	if !d.NewValueKnown("name") || !d.NewValueKnown("domain") {
+		return nil
+	}
+
+	domain := strings.TrimSuffix(dnsrecord.ToASCII(d.Get("domain").(string)), ".")
+	name := dnsrecord.NormalizeName(d.Get("name").(string), domain)
+
+	fqdn := domain
+	if name != "@" {
+		fqdn = name + "." + domain
+	}
+
+	if len(fqdn) > 253 {
+		return fmt.Errorf(
+			"expected the fully-qualified name %s to be at most 253 "+
+				"characters, got: %d. Check RFC 1035 section 2.3.4",
+			fqdn, len(fqdn),
+		)
+	}
+
+	return nil
+}
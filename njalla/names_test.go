@@ -0,0 +1,69 @@
+package njalla
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRecordName(t *testing.T) {
+	valid := []string{
+		"@",
+		"",
+		"www",
+		"www.example.com.",
+		"*",
+		"*.dev",
+		"_dmarc",
+		"_sip._tcp",
+		"s1._domainkey",
+		strings.Repeat("a", 63),
+		"bücher",
+		"*.bücher",
+		"xn--bcher-kva",
+	}
+
+	for _, name := range valid {
+		_, errs := validateRecordName(name, "name")
+		if len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %q: %v", name, errs)
+		}
+	}
+
+	invalid := []string{
+		strings.Repeat("a", 64),
+		strings.Repeat("a.", 127) + "a",
+		"www..example",
+		".www",
+		"-www",
+		"www-",
+		"dev.*",
+		"w*w",
+		"www example",
+		"xn--a",
+		"a\u200db",
+		"@.example.com",
+	}
+
+	for _, name := range invalid {
+		_, errs := validateRecordName(name, "name")
+		if len(errs) == 0 {
+			t.Fatalf("Unexpected success for %q", name)
+		}
+	}
+}
+
+func TestValidateDomain(t *testing.T) {
+	for _, domain := range []string{
+		"example.com", "bücher.example", "xn--bcher-kva.example.",
+	} {
+		if err := validateDomain(domain); err != nil {
+			t.Fatalf("Unexpected error for %q: %s", domain, err)
+		}
+	}
+
+	for _, domain := range []string{"", "exa mple.com", "xn--a.example"} {
+		if err := validateDomain(domain); err == nil {
+			t.Fatalf("Unexpected success for %q", domain)
+		}
+	}
+}
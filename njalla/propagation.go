@@ -0,0 +1,647 @@
+package njalla
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// Njalla accepts a change before its nameservers serve it. Resources with a
+// `wait_for_propagation` block query the authoritative nameservers after
+// every create and update, until each of them answers with the new content,
+// or every value of a record set, so dependent resources like ACME
+// challenges don't race the change.
+
+// defaultNameservers are Njalla's authoritative nameservers.
+var defaultNameservers = []string{
+	"1-you.njalla.no",
+	"2-can.njalla.in",
+	"3-get.njalla.fo",
+}
+
+const (
+	defaultPropagationTimeout      = "5m"
+	defaultPropagationPollInterval = "5s"
+
+	// dnsQueryTimeout bounds a single query, so an unresponsive nameserver
+	// is retried on the next poll instead of using up the whole timeout.
+	dnsQueryTimeout = 5 * time.Second
+
+	// dnsUDPSize is the UDP payload size advertised through EDNS(0).
+	dnsUDPSize = 4096
+)
+
+// Record types without a dedicated type in `dnsmessage`.
+const (
+	dnsTypeNAPTR dnsmessage.Type = 35
+	dnsTypeTLSA  dnsmessage.Type = 52
+	dnsTypeCAA   dnsmessage.Type = 257
+)
+
+// dnsTypes maps Njalla record types to their DNS types.
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CAA":   dnsTypeCAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NAPTR": dnsTypeNAPTR,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"TLSA":  dnsTypeTLSA,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// waitForPropagationSchema returns the schema of the `wait_for_propagation`
+// block shared by the record resources.
+func waitForPropagationSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Description: "Wait after creating or updating the record until the " +
+			"authoritative nameservers serve its content.",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"nameservers": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Description: "Nameservers to query, as host or " +
+						"host:port. Defaults to Njalla's nameservers.",
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: stringValidator(validateNameserver),
+					},
+				},
+				"timeout": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  defaultPropagationTimeout,
+					Description: "How long to wait for every nameserver, " +
+						"as a duration like 5m.",
+					ValidateFunc: validatePositiveDuration,
+				},
+				"poll_interval": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  defaultPropagationPollInterval,
+					Description: "How long to wait between queries to a " +
+						"nameserver, as a duration like 5s.",
+					ValidateFunc: validatePositiveDuration,
+				},
+			},
+		},
+	}
+}
+
+// validatePositiveDuration checks a value is a positive duration, as
+// accepted by `time.ParseDuration`.
+func validatePositiveDuration(val interface{}, key string) (warns []string, errs []error) {
+	duration, err := time.ParseDuration(val.(string))
+	if err != nil || duration <= 0 {
+		errs = append(errs, fmt.Errorf(
+			"expected %s to be a positive duration like 30s or 5m, got: %s",
+			key, val,
+		))
+	}
+
+	return
+}
+
+// validateNameserver checks a nameserver is a hostname or IP address, with
+// an optional port.
+func validateNameserver(v string) error {
+	host := v
+	if h, port, err := net.SplitHostPort(v); err == nil {
+		if _, err := dnsrecord.ParseUnsigned(port); err != nil {
+			return fmt.Errorf(
+				"expected nameserver port to be a number, got: %s", v,
+			)
+		}
+		host = h
+	}
+
+	if net.ParseIP(host) == nil && !dnsrecord.IsDomainName(strings.TrimSuffix(host, ".")) {
+		return fmt.Errorf(
+			"expected nameserver to be a hostname or IP address, with an "+
+				"optional port, got: %s",
+			v,
+		)
+	}
+
+	return nil
+}
+
+// propagation holds the settings of a `wait_for_propagation` block.
+type propagation struct {
+	Nameservers  []string
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// propagationSettings returns the settings of the `wait_for_propagation`
+// block of a resource, or nil if it has none.
+func propagationSettings(d *schema.ResourceData) *propagation {
+	blocks := d.Get("wait_for_propagation").([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	settings := &propagation{}
+	block, _ := blocks[0].(map[string]interface{})
+
+	nameservers, _ := block["nameservers"].([]interface{})
+	for _, nameserver := range nameservers {
+		settings.Nameservers = append(settings.Nameservers, nameserver.(string))
+	}
+	if len(settings.Nameservers) == 0 {
+		settings.Nameservers = defaultNameservers
+	}
+
+	timeout, _ := block["timeout"].(string)
+	if timeout == "" {
+		timeout = defaultPropagationTimeout
+	}
+	settings.Timeout, _ = time.ParseDuration(timeout)
+
+	interval, _ := block["poll_interval"].(string)
+	if interval == "" {
+		interval = defaultPropagationPollInterval
+	}
+	settings.PollInterval, _ = time.ParseDuration(interval)
+
+	return settings
+}
+
+// waitForPropagation waits until every nameserver of the resource's
+// `wait_for_propagation` block serves `record`, if it has one.
+func waitForPropagation(
+	ctx context.Context,
+	d *schema.ResourceData,
+	domain string,
+	record gonjalla.Record,
+	resource string,
+) diag.Diagnostics {
+	return waitForPropagationRecords(
+		ctx, d, domain, []gonjalla.Record{record}, resource,
+	)
+}
+
+// waitForPropagationRecords waits until every nameserver of the resource's
+// `wait_for_propagation` block serves all of `records`, if it has one. The
+// records must share their type and name, like the records of a set.
+func waitForPropagationRecords(
+	ctx context.Context,
+	d *schema.ResourceData,
+	domain string,
+	records []gonjalla.Record,
+	resource string,
+) diag.Diagnostics {
+	settings := propagationSettings(d)
+	if settings == nil || len(records) == 0 {
+		return nil
+	}
+
+	recordType := records[0].Type
+	name := dnsrecord.FQDN(records[0].Name, domain)
+	expected := make([]string, 0, len(records))
+	for _, record := range records {
+		expected = append(expected, propagationContent(record))
+	}
+
+	content := fmt.Sprintf("content %q", expected[0])
+	if len(expected) > 1 {
+		content = fmt.Sprintf("contents %q", expected)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, settings.Timeout)
+	defer cancel()
+
+	for _, nameserver := range settings.Nameservers {
+		last, err := pollNameserver(
+			ctx, nameserverAddress(nameserver), name, recordType, expected,
+			settings.PollInterval,
+		)
+		if err == nil {
+			continue
+		}
+
+		// The resource's own timeout, or a cancellation, can end the wait
+		// before the block's `timeout`.
+		if parentErr := parent.Err(); parentErr != nil {
+			return diag.Diagnostics{
+				{
+					Severity: diag.Error,
+					Summary: fmt.Sprintf(
+						"Stopped waiting for %s to propagate", resource,
+					),
+					Detail: fmt.Sprintf(
+						"Nameserver %s didn't serve the %s record %s with "+
+							"%s before waiting was stopped: %s. Its last "+
+							"answer was: %s",
+						nameserver, recordType, name, content, parentErr, last,
+					),
+				},
+			}
+		}
+
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary: fmt.Sprintf(
+					"Timed out waiting for %s to propagate", resource,
+				),
+				Detail: fmt.Sprintf(
+					"Nameserver %s didn't serve the %s record %s with "+
+						"%s within %s. Its last answer was: %s",
+					nameserver, recordType, name, content, settings.Timeout,
+					last,
+				),
+			},
+		}
+	}
+
+	return nil
+}
+
+// pollNameserver queries `server` every `interval` until it serves records
+// of `recordType` at `name` with every `expected` content, or the context is
+// done. It returns a description of the last answer otherwise.
+func pollNameserver(
+	ctx context.Context,
+	server string,
+	name string,
+	recordType string,
+	expected []string,
+	interval time.Duration,
+) (string, error) {
+	last := "no answer"
+
+	for {
+		served, err := queryNameserver(ctx, server, name, dnsTypes[recordType])
+		switch {
+		case err != nil:
+			last = err.Error()
+		case len(served) == 0:
+			last = "no records"
+		default:
+			last = fmt.Sprintf("%q", served)
+		}
+
+		if err == nil && servesAll(served, expected) {
+			return "", nil
+		}
+
+		tflog.Debug(ctx, "Waiting for Njalla record to propagate", map[string]interface{}{
+			"nameserver":  server,
+			"record_name": name,
+			"record_type": recordType,
+			"last_answer": last,
+		})
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// servesAll reports whether every `expected` content is in `served`.
+func servesAll(served []string, expected []string) bool {
+	for _, content := range expected {
+		found := false
+		for _, s := range served {
+			if s == content {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// queryNameserver returns the contents of the records of type `qtype` at
+// `name` served by `server`, as returned by `answerContent`. The query goes
+// over UDP, and is retried over TCP if the answer is truncated. A name that
+// doesn't exist has no records.
+func queryNameserver(
+	ctx context.Context, server string, name string, qtype dnsmessage.Type,
+) ([]string, error) {
+	dnsName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt dnsmessage.ResourceHeader
+	opt.SetEDNS0(dnsUDPSize, dnsmessage.RCodeSuccess, false)
+
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: uint16(rand.Intn(1 << 16))},
+		Questions: []dnsmessage.Question{
+			{Name: dnsName, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: opt, Body: &dnsmessage.OPTResource{}},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	response, err := exchangeUDP(ctx, server, packed, query.ID)
+	if err == nil && response.Truncated {
+		response, err = exchangeTCP(ctx, server, packed, query.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s answered with %s", server, response.RCode)
+	}
+
+	var served []string
+	for _, answer := range response.Answers {
+		if answer.Header.Type != qtype ||
+			!dnsrecord.EqualNames(answer.Header.Name.String(), name) {
+			continue
+		}
+
+		if content, ok := answerContent(answer.Body); ok {
+			served = append(served, content)
+		}
+	}
+
+	return served, nil
+}
+
+// exchangeUDP sends a query over UDP and returns the response with the
+// query's ID.
+func exchangeUDP(
+	ctx context.Context, server string, query []byte, id uint16,
+) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, dnsUDPSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var response dnsmessage.Message
+		if err := response.Unpack(buf[:n]); err != nil || response.ID != id {
+			// Stray or malformed packets are ignored, like late answers
+			// to a previous query.
+			continue
+		}
+
+		return &response, nil
+	}
+}
+
+// exchangeTCP sends a query over TCP and returns the response, which must
+// have the query's ID.
+func exchangeTCP(
+	ctx context.Context, server string, query []byte, id uint16,
+) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	framed := make([]byte, 2, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if response.ID != id {
+		return nil, errors.New("response ID doesn't match the query")
+	}
+
+	return &response, nil
+}
+
+// nameserverAddress returns the `host:port` address of a nameserver,
+// defaulting to port 53.
+func nameserverAddress(nameserver string) string {
+	if _, _, err := net.SplitHostPort(nameserver); err == nil {
+		return nameserver
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(nameserver, "."), "53")
+}
+
+// canonicalDNSName returns a name in lower case and without its trailing dot,
+// keeping the root as `.`.
+func canonicalDNSName(name string) string {
+	if name == "." {
+		return name
+	}
+
+	return strings.ToLower(strings.TrimSuffix(dnsrecord.ToASCII(name), "."))
+}
+
+// propagationContent returns the content of a record in the form
+// `answerContent` returns it for the same record served by a nameserver.
+func propagationContent(record gonjalla.Record) string {
+	switch record.Type {
+	case "A", "AAAA":
+		if ip := net.ParseIP(record.Content); ip != nil {
+			return ip.String()
+		}
+	case "CNAME", "NS", "PTR":
+		return canonicalDNSName(record.Content)
+	case "MX":
+		priority := 0
+		if record.Priority != nil {
+			priority = *record.Priority
+		}
+
+		return fmt.Sprintf("%d %s", priority, canonicalDNSName(record.Content))
+	case "TXT":
+		if text, err := dnsrecord.ParseTXT(record.Content); err == nil {
+			return text
+		}
+	case "CAA":
+		if parsed, err := dnsrecord.ParseCAA(record.Content); err == nil {
+			return parsed.String()
+		}
+	case "TLSA":
+		if parsed, err := dnsrecord.ParseTLSA(record.Content); err == nil {
+			parsed.Data = strings.ToLower(parsed.Data)
+			return parsed.String()
+		}
+	case "NAPTR":
+		if parsed, err := dnsrecord.ParseNAPTR(record.Content); err == nil {
+			parsed.Flags = strings.ToLower(parsed.Flags)
+			parsed.Replacement = canonicalDNSName(parsed.Replacement)
+			return parsed.String()
+		}
+	}
+
+	return record.Content
+}
+
+// answerContent returns the content of a record served by a nameserver, in
+// a canonical form comparable with `propagationContent`.
+func answerContent(body dnsmessage.ResourceBody) (string, bool) {
+	switch b := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(b.A[:]).String(), true
+	case *dnsmessage.AAAAResource:
+		return net.IP(b.AAAA[:]).String(), true
+	case *dnsmessage.CNAMEResource:
+		return canonicalDNSName(b.CNAME.String()), true
+	case *dnsmessage.NSResource:
+		return canonicalDNSName(b.NS.String()), true
+	case *dnsmessage.PTRResource:
+		return canonicalDNSName(b.PTR.String()), true
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", b.Pref, canonicalDNSName(b.MX.String())), true
+	case *dnsmessage.TXTResource:
+		return strings.Join(b.TXT, ""), true
+	case *dnsmessage.UnknownResource:
+		switch b.Type {
+		case dnsTypeCAA:
+			return decodeCAA(b.Data)
+		case dnsTypeTLSA:
+			return decodeTLSA(b.Data)
+		case dnsTypeNAPTR:
+			return decodeNAPTR(b.Data)
+		}
+	}
+
+	return "", false
+}
+
+// decodeCAA returns the content of CAA RDATA, as defined in RFC 8659
+// section 4.1.
+func decodeCAA(data []byte) (string, bool) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "", false
+	}
+
+	tagEnd := 2 + int(data[1])
+	return dnsrecord.CAAContent{
+		Flags: int(data[0]),
+		Tag:   strings.ToLower(string(data[2:tagEnd])),
+		Value: string(data[tagEnd:]),
+	}.String(), true
+}
+
+// decodeTLSA returns the content of TLSA RDATA, as defined in RFC 6698
+// section 2.1.
+func decodeTLSA(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+
+	return dnsrecord.TLSAContent{
+		Usage:        int(data[0]),
+		Selector:     int(data[1]),
+		MatchingType: int(data[2]),
+		Data:         hex.EncodeToString(data[3:]),
+	}.String(), true
+}
+
+// decodeNAPTR returns the content of NAPTR RDATA, as defined in RFC 3403
+// section 4.1. The replacement is never compressed.
+func decodeNAPTR(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+
+	parsed := dnsrecord.NAPTRContent{
+		Order:      int(binary.BigEndian.Uint16(data[0:2])),
+		Preference: int(binary.BigEndian.Uint16(data[2:4])),
+	}
+	data = data[4:]
+
+	for _, field := range []*string{&parsed.Flags, &parsed.Service, &parsed.Regexp} {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return "", false
+		}
+		*field = string(data[1 : 1+int(data[0])])
+		data = data[1+int(data[0]):]
+	}
+	parsed.Flags = strings.ToLower(parsed.Flags)
+
+	var labels []string
+	for {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return "", false
+		}
+		if data[0] == 0 {
+			break
+		}
+		labels = append(labels, string(data[1:1+int(data[0])]))
+		data = data[1+int(data[0]):]
+	}
+	parsed.Replacement = canonicalDNSName(strings.Join(labels, ".") + ".")
+
+	return parsed.String(), true
+}
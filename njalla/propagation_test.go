@@ -0,0 +1,315 @@
+package njalla
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+// testPropagationData returns the data of a TXT record waiting for its
+// propagation to `nameserver`.
+func testPropagationData(
+	t *testing.T, nameserver string, timeout string,
+) *schema.ResourceData {
+	return schema.TestResourceDataRaw(
+		t, resourceRecordTXT().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "_acme-challenge",
+			"ttl":     "60",
+			"content": "token",
+			"wait_for_propagation": []interface{}{
+				map[string]interface{}{
+					"nameservers":   []interface{}{nameserver},
+					"timeout":       timeout,
+					"poll_interval": "10ms",
+				},
+			},
+		},
+	)
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	server := njallatest.NewDNSServer()
+	defer server.Close()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.Set(
+			"_acme-challenge.testing.com",
+			&dnsmessage.TXTResource{TXT: []string{"old"}},
+			&dnsmessage.TXTResource{TXT: []string{"to", "ken"}},
+		)
+	}()
+
+	d := testPropagationData(t, server.Addr, "5s")
+	record := gonjalla.Record{
+		Name: "_acme-challenge", Type: "TXT", Content: dnsrecord.FormatTXT("token"),
+	}
+
+	diags := waitForPropagation(
+		context.Background(), d, "testing.com", record, "njalla_record_txt",
+	)
+	if diags.HasError() {
+		t.Fatalf("Waiting failed: %v", diags)
+	}
+
+	if server.Queries() < 2 {
+		t.Fatalf("Expected the nameserver polled, got %d queries", server.Queries())
+	}
+}
+
+func TestWaitForPropagationTimeout(t *testing.T) {
+	server := njallatest.NewDNSServer()
+	defer server.Close()
+
+	server.Set(
+		"_acme-challenge.testing.com",
+		&dnsmessage.TXTResource{TXT: []string{"old"}},
+	)
+
+	d := testPropagationData(t, server.Addr, "100ms")
+	record := gonjalla.Record{
+		Name: "_acme-challenge", Type: "TXT", Content: dnsrecord.FormatTXT("token"),
+	}
+
+	diags := waitForPropagation(
+		context.Background(), d, "testing.com", record, "njalla_record_txt",
+	)
+	if !diags.HasError() {
+		t.Fatal("Expected waiting to time out")
+	}
+	if !strings.Contains(diags[0].Detail, `["old"]`) {
+		t.Fatalf("Expected the last answer in the error, got %s", diags[0].Detail)
+	}
+}
+
+func TestWaitForPropagationContextDone(t *testing.T) {
+	server := njallatest.NewDNSServer()
+	defer server.Close()
+
+	d := testPropagationData(t, server.Addr, "5s")
+	record := gonjalla.Record{
+		Name: "_acme-challenge", Type: "TXT", Content: dnsrecord.FormatTXT("token"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	diags := waitForPropagation(ctx, d, "testing.com", record, "njalla_record_txt")
+	if !diags.HasError() {
+		t.Fatal("Expected waiting to stop with the context")
+	}
+	if strings.Contains(diags[0].Detail, "within 5s") {
+		t.Fatalf("Expected the context error, not the timeout: %s", diags[0].Detail)
+	}
+	if !strings.Contains(diags[0].Detail, context.DeadlineExceeded.Error()) {
+		t.Fatalf("Expected the context error in the error, got %s", diags[0].Detail)
+	}
+}
+
+func TestWaitForPropagationRecordSet(t *testing.T) {
+	server := njallatest.NewDNSServer()
+	defer server.Close()
+
+	server.Set(
+		"testing.com",
+		&dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.testing.com.")},
+	)
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordSet().Schema, map[string]interface{}{
+			"domain": "testing.com",
+			"name":   "@",
+			"type":   "NS",
+			"ttl":    "60",
+			"values": []interface{}{
+				map[string]interface{}{"value": "ns1.testing.com."},
+				map[string]interface{}{"value": "ns2.testing.com."},
+			},
+			"wait_for_propagation": []interface{}{
+				map[string]interface{}{
+					"nameservers":   []interface{}{server.Addr},
+					"timeout":       "100ms",
+					"poll_interval": "10ms",
+				},
+			},
+		},
+	)
+	records := recordSetValueRecords(d, "NS", "@", 60)
+
+	// Only one of the values is served.
+	diags := waitForPropagationRecords(
+		context.Background(), d, "testing.com", records, "njalla_record_set",
+	)
+	if !diags.HasError() {
+		t.Fatal("Expected waiting to time out with a value missing")
+	}
+
+	server.Set(
+		"testing.com",
+		&dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns2.testing.com.")},
+		&dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.testing.com.")},
+	)
+
+	diags = waitForPropagationRecords(
+		context.Background(), d, "testing.com", records, "njalla_record_set",
+	)
+	if diags.HasError() {
+		t.Fatalf("Waiting failed: %v", diags)
+	}
+}
+
+func TestWaitForPropagationDisabled(t *testing.T) {
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordTXT().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "60",
+			"content": "token",
+		},
+	)
+
+	if propagationSettings(d) != nil {
+		t.Fatal("Expected no propagation settings without the block")
+	}
+}
+
+func TestUpdateOnlyWaitForPropagation(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	config.OwnerID = "team-a"
+
+	existing := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "NS", Name: "@", Content: "ns1.example.com", TTL: 10800,
+	})
+	state := &terraform.InstanceState{
+		ID: existing.ID,
+		Attributes: map[string]string{
+			"domain":  "testing.com",
+			"name":    "@",
+			"ttl":     "10800",
+			"content": "ns1.example.com",
+		},
+	}
+	d := testResourceDataUpdate(t, resourceRecordNS(), state, map[string]interface{}{
+		"domain":  "testing.com",
+		"name":    "@",
+		"ttl":     "10800",
+		"content": "ns1.example.com",
+		"wait_for_propagation": []interface{}{
+			map[string]interface{}{"timeout": "1m"},
+		},
+	})
+
+	// The unmarked record would be refused by the ownership registry if it
+	// were edited.
+	if diags := resourceRecordNSUpdate(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Update failed: %v", diags)
+	}
+	if server.CallCount("edit-record") != 0 {
+		t.Fatal("Unexpected edit of the record")
+	}
+}
+
+func TestAnswerContent(t *testing.T) {
+	priority := 10
+
+	cases := []struct {
+		record gonjalla.Record
+		body   dnsmessage.ResourceBody
+	}{
+		{
+			gonjalla.Record{Type: "AAAA", Content: "2001:DB8::0001"},
+			&dnsmessage.AAAAResource{AAAA: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 15: 1,
+			}},
+		},
+		{
+			gonjalla.Record{Type: "CNAME", Content: "Target.Example.com."},
+			&dnsmessage.CNAMEResource{
+				CNAME: dnsmessage.MustNewName("target.example.com."),
+			},
+		},
+		{
+			gonjalla.Record{
+				Type: "MX", Content: "mail.example.com", Priority: &priority,
+			},
+			&dnsmessage.MXResource{
+				Pref: 10, MX: dnsmessage.MustNewName("mail.example.com."),
+			},
+		},
+		{
+			gonjalla.Record{
+				Type: "CAA", Content: `0 ISSUE "letsencrypt.org"`,
+			},
+			&dnsmessage.UnknownResource{
+				Type: dnsTypeCAA,
+				Data: append(
+					[]byte{0, 5}, []byte("issueletsencrypt.org")...,
+				),
+			},
+		},
+		{
+			gonjalla.Record{
+				Type:    "TLSA",
+				Content: "3 1 1 " + strings.Repeat("AB", 32),
+			},
+			&dnsmessage.UnknownResource{
+				Type: dnsTypeTLSA,
+				Data: append(
+					[]byte{3, 1, 1}, []byte(strings.Repeat("\xab", 32))...,
+				),
+			},
+		},
+		{
+			gonjalla.Record{
+				Type:    "NAPTR",
+				Content: `100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+			},
+			&dnsmessage.UnknownResource{
+				Type: dnsTypeNAPTR,
+				Data: []byte(
+					"\x00\x64\x00\x0a\x01s\x07SIP+D2U\x00" +
+						"\x04_sip\x04_udp\x07example\x03com\x00",
+				),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		expected := propagationContent(c.record)
+		served, ok := answerContent(c.body)
+		if !ok || served != expected {
+			t.Errorf(
+				"%s record %q served as %q, expected %q",
+				c.record.Type, c.record.Content, served, expected,
+			)
+		}
+	}
+}
+
+func TestNameserverAddress(t *testing.T) {
+	cases := map[string]string{
+		"1-you.njalla.no":  "1-you.njalla.no:53",
+		"ns1.example.com.": "ns1.example.com:53",
+		"127.0.0.1:5353":   "127.0.0.1:5353",
+		"::1":              "[::1]:53",
+	}
+
+	for nameserver, expected := range cases {
+		if address := nameserverAddress(nameserver); address != expected {
+			t.Errorf(
+				"nameserverAddress(%q) = %q, expected %q",
+				nameserver, address, expected,
+			)
+		}
+	}
+}
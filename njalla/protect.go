@@ -0,0 +1,140 @@
+package njalla
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// Records matching the provider's `protected_records` can't be deleted or
+// renamed, unless the provider is configured with
+// `allow_protected_deletion` for the run. Unlike `prevent_destroy`, the
+// protection doesn't live next to the resource, so it survives refactors of
+// the configuration.
+
+// apexNSPattern protects the apex NS records, without which the whole domain
+// goes offline. It's added to `protected_records` unless `protect_apex_ns`
+// is false.
+var apexNSPattern = recordPattern{Type: "NS", Name: "@"}
+
+// recordPattern matches records by type and name. Both are shell patterns
+// as accepted by `path.Match`, the type compared in upper case and the name
+// in its canonical form. A name with a trailing dot is fully qualified, and
+// only matches records of the domain it ends with.
+type recordPattern struct {
+	Type string
+	Name string
+}
+
+// String returns the pattern as written in `protected_records`.
+func (p recordPattern) String() string {
+	return p.Type + ":" + p.Name
+}
+
+// matches reports whether the pattern matches a record of `recordType` with
+// canonical name `name` in `domain`.
+func (p recordPattern) matches(recordType string, name string, domain string) bool {
+	if strings.HasSuffix(p.Name, ".") {
+		name = dnsrecord.FQDN(name, domain)
+	}
+
+	typeMatch, _ := path.Match(p.Type, strings.ToUpper(recordType))
+	nameMatch, _ := path.Match(strings.ToLower(p.Name), strings.ToLower(name))
+
+	return typeMatch && nameMatch
+}
+
+// parseRecordPattern parses a pattern like `NS:@`, `MX:*` or `*:www`.
+func parseRecordPattern(v string) (recordPattern, error) {
+	parts := strings.SplitN(v, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return recordPattern{}, fmt.Errorf(
+			"expected a record pattern like NS:@ or *:www, got: %s", v,
+		)
+	}
+
+	pattern := recordPattern{
+		Type: strings.ToUpper(parts[0]),
+		Name: dnsrecord.NormalizeName(parts[1], ""),
+	}
+	name := strings.TrimSpace(parts[1])
+	if len(name) > 1 && strings.HasSuffix(name, ".") {
+		pattern.Name = strings.ToLower(pattern.Name) + "."
+	}
+
+	for _, p := range []string{pattern.Type, pattern.Name} {
+		if _, err := path.Match(p, ""); err != nil {
+			return recordPattern{}, fmt.Errorf(
+				"expected a record pattern like NS:@ or *:www, got: %s. %s",
+				v, err,
+			)
+		}
+	}
+
+	return pattern, nil
+}
+
+// parseRecordPatterns parses every pattern of `protected_records`, adding
+// `apexNSPattern` if `apexNS` is set.
+func parseRecordPatterns(values []interface{}, apexNS bool) ([]recordPattern, error) {
+	patterns := make([]recordPattern, 0, len(values)+1)
+	if apexNS {
+		patterns = append(patterns, apexNSPattern)
+	}
+
+	for _, v := range values {
+		pattern, err := parseRecordPattern(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// validateRecordPattern checks a pattern of `protected_records`.
+func validateRecordPattern(val interface{}, key string) (warns []string, errs []error) {
+	if _, err := parseRecordPattern(val.(string)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return
+}
+
+// checkDeletable returns an error diagnostic if a record of `recordType` at
+// `name`, in canonical form, is protected and protected deletion isn't
+// allowed.
+func (c *Config) checkDeletable(
+	domain string, recordType string, name string, resource string,
+) diag.Diagnostics {
+	if c.AllowProtectedDeletion {
+		return nil
+	}
+
+	for _, pattern := range c.ProtectedRecords {
+		if !pattern.matches(recordType, name, domain) {
+			continue
+		}
+
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Refusing to delete protected %s", resource),
+				Detail: fmt.Sprintf(
+					"The %s record %s in domain %s matches the protected "+
+						"records pattern %s. Set allow_protected_deletion = "+
+						"true in the provider (or NJALLA_ALLOW_PROTECTED_DELETION) "+
+						"for this run to delete it.",
+					recordType, name, domain, pattern,
+				),
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package njalla
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/Sighery/gonjalla"
+)
+
+func TestParseRecordPattern(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected recordPattern
+		valid    bool
+	}{
+		{"NS:@", recordPattern{"NS", "@"}, true},
+		{"mx:@", recordPattern{"MX", "@"}, true},
+		{"*:www", recordPattern{"*", "www"}, true},
+		{"A:*.prod", recordPattern{"A", "*.prod"}, true},
+		{"NS:Testing.com.", recordPattern{"NS", "testing.com."}, true},
+		{"A:*.prod.testing.com.", recordPattern{"A", "*.prod.testing.com."}, true},
+		{"NS", recordPattern{}, false},
+		{"NS:", recordPattern{}, false},
+		{":@", recordPattern{}, false},
+		{"A:[www", recordPattern{}, false},
+	}
+
+	for _, c := range cases {
+		pattern, err := parseRecordPattern(c.input)
+		if c.valid && err != nil {
+			t.Errorf("parseRecordPattern(%q) returned an error: %s", c.input, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("parseRecordPattern(%q) = %v, expected an error", c.input, pattern)
+		} else if pattern != c.expected {
+			t.Errorf(
+				"parseRecordPattern(%q) = %v, expected %v",
+				c.input, pattern, c.expected,
+			)
+		}
+	}
+}
+
+func TestParseRecordPatternsDefault(t *testing.T) {
+	patterns, err := parseRecordPatterns(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patterns) != 1 || patterns[0] != (recordPattern{"NS", "@"}) {
+		t.Fatalf("Expected the apex NS records protected, got %v", patterns)
+	}
+}
+
+func TestParseRecordPatternsApexNSDisabled(t *testing.T) {
+	patterns, err := parseRecordPatterns([]interface{}{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patterns) != 0 {
+		t.Fatalf("Expected no records protected, got %v", patterns)
+	}
+}
+
+func TestRecordPatternMatches(t *testing.T) {
+	cases := []struct {
+		pattern    recordPattern
+		recordType string
+		name       string
+		domain     string
+		expected   bool
+	}{
+		{recordPattern{"NS", "@"}, "NS", "@", "testing.com", true},
+		{recordPattern{"NS", "@"}, "NS", "dev", "testing.com", false},
+		{recordPattern{"NS", "@"}, "A", "@", "testing.com", false},
+		{recordPattern{"*", "www"}, "CNAME", "WWW", "testing.com", true},
+		{recordPattern{"A", "*.prod"}, "A", "api.prod", "testing.com", true},
+		{recordPattern{"A", "*.prod"}, "A", "prod", "testing.com", false},
+		{recordPattern{"NS", "testing.com."}, "NS", "@", "testing.com", true},
+		{recordPattern{"NS", "testing.com."}, "NS", "@", "example.com", false},
+		{recordPattern{"NS", "testing.com."}, "NS", "dev", "testing.com", false},
+		{recordPattern{"A", "*.prod.testing.com."}, "A", "api.prod", "testing.com", true},
+		{recordPattern{"A", "*.prod.testing.com."}, "A", "api.prod", "example.com", false},
+	}
+
+	for _, c := range cases {
+		matches := c.pattern.matches(c.recordType, c.name, c.domain)
+		if matches != c.expected {
+			t.Errorf(
+				"%s matches %s %s in %s = %t, expected %t",
+				c.pattern, c.recordType, c.name, c.domain, matches, c.expected,
+			)
+		}
+	}
+}
+
+func TestDeleteProtectedRecord(t *testing.T) {
+	config := newTestConfig(t, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Unexpected API request deleting a protected record")
+		},
+	))
+	config.ProtectedRecords, _ = parseRecordPatterns(nil, true)
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordNS().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "testing.com.",
+			"ttl":     "10800",
+			"content": "ns1.example.com",
+		},
+	)
+	d.SetId("1234")
+
+	diags := resourceRecordNSDelete(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Fatal("Expected Delete to fail for the apex NS record")
+	}
+}
+
+func TestDeleteProtectedRecordAllowed(t *testing.T) {
+	config, server := newTestRegistry(t, "")
+	config.ProtectedRecords, _ = parseRecordPatterns(nil, true)
+	config.AllowProtectedDeletion = true
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordNS().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "@",
+			"ttl":     "10800",
+			"content": "ns1.example.com",
+		},
+	)
+
+	ctx := context.Background()
+	if diags := resourceRecordNSCreate(ctx, d, config); diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+	if diags := resourceRecordNSDelete(ctx, d, config); diags.HasError() {
+		t.Fatalf("Delete failed: %v", diags)
+	}
+
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected the record removed, got %v", records)
+	}
+}
+
+// testResourceDataUpdate returns the resource data of an update of `state`
+// to the configuration `raw`.
+func testResourceDataUpdate(
+	t *testing.T,
+	r *schema.Resource,
+	state *terraform.InstanceState,
+	raw map[string]interface{},
+) *schema.ResourceData {
+	t.Helper()
+
+	sm := schema.InternalMap(r.Schema)
+	diff, err := sm.Diff(
+		context.Background(), state, terraform.NewResourceConfigRaw(raw),
+		nil, nil, false,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := sm.Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestUpdateProtectedRecord(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous string
+		updated  string
+		refused  bool
+	}{
+		{"edit", "@", "@", false},
+		{"rename away", "@", "dev", true},
+		{"rename onto", "dev", "@", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, server := newTestCRUDConfig(t)
+			config.ProtectedRecords, _ = parseRecordPatterns(
+				[]interface{}{"NS:testing.com."}, false,
+			)
+
+			existing := server.AddRecord("testing.com", gonjalla.Record{
+				Type: "NS", Name: c.previous, Content: "ns1.example.com",
+				TTL: 10800,
+			})
+			state := &terraform.InstanceState{
+				ID: existing.ID,
+				Attributes: map[string]string{
+					"domain":  "testing.com",
+					"name":    c.previous,
+					"ttl":     "10800",
+					"content": "ns1.example.com",
+				},
+			}
+			d := testResourceDataUpdate(t, resourceRecordNS(), state, map[string]interface{}{
+				"domain":  "testing.com",
+				"name":    c.updated,
+				"ttl":     "10800",
+				"content": "ns2.example.com",
+			})
+
+			diags := resourceRecordNSUpdate(context.Background(), d, config)
+			if diags.HasError() != c.refused {
+				t.Fatalf("Expected the update refused: %t, got %v", c.refused, diags)
+			}
+			if c.refused && server.CallCount("edit-record") != 0 {
+				t.Fatal("Unexpected edit of a protected record")
+			}
+		})
+	}
+}
+
+func TestProviderProtectApexNS(t *testing.T) {
+	cases := []struct {
+		name     string
+		raw      map[string]interface{}
+		expected int
+	}{
+		{"default", map[string]interface{}{}, 1},
+		{"disabled", map[string]interface{}{"protect_apex_ns": false}, 0},
+		{
+			"disabled with patterns",
+			map[string]interface{}{
+				"protect_apex_ns":   false,
+				"protected_records": []interface{}{"A:@"},
+			},
+			1,
+		},
+		{
+			"patterns",
+			map[string]interface{}{"protected_records": []interface{}{"A:@"}},
+			2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.raw["api_token"] = "test-token"
+
+			provider := Provider()
+			diags := provider.Configure(
+				context.Background(), terraform.NewResourceConfigRaw(c.raw),
+			)
+			if diags.HasError() {
+				t.Fatalf("Unexpected diagnostics: %v", diags)
+			}
+
+			patterns := provider.Meta().(*Config).ProtectedRecords
+			if len(patterns) != c.expected {
+				t.Fatalf(
+					"Expected %d protected records patterns, got %v",
+					c.expected, patterns,
+				)
+			}
+		})
+	}
+}
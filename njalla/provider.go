@@ -5,6 +5,9 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
 )
 
 // Provider for Njalla resources
@@ -17,18 +20,114 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("NJALLA_API_TOKEN", nil),
 				Description: "Njalla API token",
 			},
+			"api_endpoint": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("NJALLA_API_ENDPOINT", api.DefaultEndpoint),
+				Description: "Njalla JSON-RPC API endpoint",
+			},
+			"read_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: envBoolDefaultFunc("NJALLA_READ_ONLY", false),
+				Description: "Refuse every create, update and delete",
+			},
+			"requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "Maximum Njalla API requests per second, 0 for no limit",
+			},
+			"burst": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum burst of Njalla API requests",
+			},
+			"default_ttl": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "10800",
+				ValidateFunc: validateDefaultTTL,
+				Description:  "TTL of records without one, in seconds or as a duration like 3h",
+			},
+			"ttl_rounding": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  ttlRoundingNone,
+				ValidateFunc: validation.StringInSlice(
+					[]string{ttlRoundingNone, ttlRoundingNearest}, false,
+				),
+				Description: "How TTLs Njalla doesn't accept are handled: none fails the plan, nearest rounds them",
+			},
+			"adopt_existing": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Adopt identical existing records on create instead of adding new ones",
+			},
+			"cname_conflicts": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  cnameConflictsError,
+				ValidateFunc: validation.StringInSlice(
+					[]string{cnameConflictsError, cnameConflictsWait}, false,
+				),
+				Description: "How records conflicting with a CNAME are handled: error fails the plan, wait waits for them to be removed when applied",
+			},
+			"owner_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("NJALLA_OWNER_ID", ""),
+				ValidateFunc: validateOwnerID,
+				Description:  "Owner of the records this provider creates, enabling the TXT ownership registry",
+			},
+			"claim_unmarked_records": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Claim records without an ownership marker for owner_id instead of refusing to change them",
+			},
+			"protected_records": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateRecordPattern,
+				},
+				Description: "TYPE:name patterns of records that can't be deleted",
+			},
+			"protect_apex_ns": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Protect the apex NS records, as if NS:@ was in protected_records",
+			},
+			"allow_protected_deletion": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: envBoolDefaultFunc("NJALLA_ALLOW_PROTECTED_DELETION", false),
+				Description: "Allow deleting records matching protected_records",
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"njalla_record_txt":   resourceRecordTXT(),
-			"njalla_record_a":     resourceRecordA(),
-			"njalla_record_aaaa":  resourceRecordAAAA(),
-			"njalla_record_mx":    resourceRecordMX(),
-			"njalla_record_cname": resourceRecordCNAME(),
-			"njalla_record_caa":   resourceRecordCAA(),
-			"njalla_record_ptr":   resourceRecordPTR(),
-			"njalla_record_ns":    resourceRecordNS(),
-			"njalla_record_tlsa":  resourceRecordTLSA(),
-			"njalla_record_naptr": resourceRecordNAPTR(),
+			"njalla_acme_challenge": resourceACMEChallenge(),
+			"njalla_record_txt":     resourceRecordTXT(),
+			"njalla_record_a":       resourceRecordA(),
+			"njalla_record_aaaa":    resourceRecordAAAA(),
+			"njalla_record_mx":      resourceRecordMX(),
+			"njalla_record_cname":   resourceRecordCNAME(),
+			"njalla_record_caa":     resourceRecordCAA(),
+			"njalla_record_ptr":     resourceRecordPTR(),
+			"njalla_record_ns":      resourceRecordNS(),
+			"njalla_record_tlsa":    resourceRecordTLSA(),
+			"njalla_record_naptr":   resourceRecordNAPTR(),
+			"njalla_record_set":     resourceRecordSet(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"njalla_zone_drift": dataSourceZoneDrift(),
 		},
 		ConfigureContextFunc: providerConfigure,
 	}
@@ -39,8 +138,38 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 
 	if v, ok := d.GetOk("api_token"); ok {
 		token := v.(string)
+
+		client := api.NewClient(token)
+		client.Endpoint = d.Get("api_endpoint").(string)
+		client.Limiter = newLimiter(
+			d.Get("requests_per_second").(float64),
+			d.Get("burst").(int),
+		)
+
+		// Already validated by `validateDefaultTTL`.
+		ttl, _ := parseTTL(d.Get("default_ttl").(string))
+
+		protected, err := parseRecordPatterns(
+			d.Get("protected_records").([]interface{}),
+			d.Get("protect_apex_ns").(bool),
+		)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+
 		config := Config{
-			Token: token,
+			Client:      client,
+			ReadOnly:    d.Get("read_only").(bool),
+			DefaultTTL:  ttl,
+			TTLRounding: d.Get("ttl_rounding").(string),
+
+			AdoptExisting:  d.Get("adopt_existing").(bool),
+			CNAMEConflicts: d.Get("cname_conflicts").(string),
+			OwnerID:        d.Get("owner_id").(string),
+			ClaimUnmarked:  d.Get("claim_unmarked_records").(bool),
+
+			ProtectedRecords:       protected,
+			AllowProtectedDeletion: d.Get("allow_protected_deletion").(bool),
 		}
 
 		return &config, diags
@@ -1,15 +1,37 @@
 package njalla
 
 import (
+	"flag"
+	"fmt"
 	"os"
+	"os/exec"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+// Token and domain used when the acceptance tests run against the fake
+// Njalla API instead of the live one.
+const (
+	testAccMockToken  = "testacc-mock-token"
+	testAccMockDomain = "testacc-mock.com"
+
+	// testAccMockIDNDomain is an internationalized domain registered in the
+	// fake API, in ASCII form, for tests of Unicode domains.
+	testAccMockIDNDomain = "xn--testacc-bcher-4ob.com"
 )
 
 var testAccProviders map[string]*schema.Provider
 var testAccProvider *schema.Provider
 
+// testAccMockServer is the fake Njalla API the acceptance tests run against
+// when `TF_ACC` isn't set. It's nil when running against the live API.
+var testAccMockServer *njallatest.Server
+
 func init() {
 	testAccProvider = Provider()
 	testAccProviders = map[string]*schema.Provider{
@@ -17,6 +39,73 @@ func init() {
 	}
 }
 
+// TestMain runs the acceptance tests against the live Njalla API when
+// `TF_ACC` is set, like any Terraform provider. Otherwise they run against an
+// in-process fake of the API, as long as a Terraform CLI is available. In CI
+// (`CI` set) a missing Terraform CLI fails the run instead of silently
+// skipping the acceptance tests.
+//
+// Sweepers (`go test -sweep`) always run against the live API.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	sweeping := flag.Lookup("sweep").Value.String() != ""
+
+	if !sweeping && os.Getenv(resource.EnvTfAcc) == "" {
+		if testAccTerraformAvailable() {
+			// The fake is left running until the process exits, as
+			// `resource.TestMain` never returns.
+			testAccMockServer = njallatest.NewServer(testAccMockToken)
+			testAccMockServer.AddDomain(testAccMockDomain)
+			testAccMockServer.AddDomain(testAccMockIDNDomain)
+
+			os.Setenv(resource.EnvTfAcc, "1")
+			os.Setenv("NJALLA_API_TOKEN", testAccMockToken)
+			os.Setenv("NJALLA_API_ENDPOINT", testAccMockServer.URL)
+			os.Setenv("NJALLA_TESTACC_DOMAIN", testAccMockDomain)
+		} else if os.Getenv("CI") != "" {
+			fmt.Fprintln(
+				os.Stderr,
+				"Refusing to skip the acceptance tests in CI.",
+			)
+			os.Exit(1)
+		}
+	}
+
+	resource.TestMain(m)
+}
+
+// testAccTerraformAvailable reports whether the acceptance test framework
+// can find a Terraform CLI without downloading one.
+func testAccTerraformAvailable() bool {
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") != "" {
+		return true
+	}
+	if os.Getenv("TF_ACC_TERRAFORM_VERSION") != "" {
+		return true
+	}
+	if _, err := exec.LookPath("terraform"); err == nil {
+		return true
+	}
+
+	fmt.Fprintln(
+		os.Stderr,
+		"No Terraform CLI found, skipping acceptance tests against the fake "+
+			"Njalla API. Install Terraform or set TF_ACC_TERRAFORM_PATH.",
+	)
+	return false
+}
+
+// testAccAPIClient returns a client for the API the acceptance tests run
+// against, for tests changing records behind the provider's back.
+func testAccAPIClient() *api.Client {
+	client := api.NewClient(os.Getenv("NJALLA_API_TOKEN"))
+	if endpoint := os.Getenv("NJALLA_API_ENDPOINT"); endpoint != "" {
+		client.Endpoint = endpoint
+	}
+
+	return client
+}
+
 func testAccPreCheck(t *testing.T) {
 	if v := os.Getenv("NJALLA_API_TOKEN"); v == "" {
 		t.Fatal("NJALLA_API_TOKEN must be set for acceptance tests")
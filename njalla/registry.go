@@ -0,0 +1,384 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// When the provider is configured with an `owner_id`, records are tracked in
+// an ownership registry modelled on external-dns's TXT registry. Every name
+// and type with managed records gets a companion TXT record, its marker,
+// naming the owner. Records whose marker names a different owner are never
+// created, edited or deleted, so zones can be shared with other tools and
+// other Terraform configurations.
+//
+// The marker of `www`'s A records is the TXT record `_njalla-owner-a.www`,
+// and the marker of the apex's is `_njalla-owner-a`. A leading wildcard is
+// replaced by `_wildcard`, as it must be the leftmost label.
+//
+// A marker covers every record of its type at its name, so records without
+// one, like those created by another tool or before the registry was
+// enabled, are treated as foreign too: adding, editing or deleting records
+// next to them would take all of them over. With the provider's
+// `claim_unmarked_records` they're claimed instead. Otherwise a marker is
+// only added when the first record of its type is created at its name.
+
+const (
+	// ownerMarkerPrefix starts the leftmost label of every marker.
+	ownerMarkerPrefix = "_njalla-owner-"
+
+	// ownerMarkerHeritage starts the text of every marker, telling them
+	// apart from other TXT records.
+	ownerMarkerHeritage = "heritage=terraform-provider-njalla"
+)
+
+// ownerMarkerName returns the name of the marker of the records of
+// `recordType` at `name`, given in canonical form.
+func ownerMarkerName(recordType string, name string) string {
+	label := ownerMarkerPrefix + strings.ToLower(recordType)
+
+	if name == "@" {
+		return label
+	}
+
+	if name == "*" || strings.HasPrefix(name, "*.") {
+		name = "_wildcard" + name[1:]
+	}
+
+	return label + "." + name
+}
+
+// validateOwnerID checks an owner ID can be stored in a marker: letters,
+// digits, hyphens, underscores and dots.
+func validateOwnerID(val interface{}, key string) (warns []string, errs []error) {
+	for _, c := range val.(string) {
+		switch {
+		case 'a' <= c && c <= 'z':
+		case 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.':
+		default:
+			errs = append(errs, fmt.Errorf(
+				"expected %s to only contain letters, digits, hyphens, "+
+					"underscores and dots, got: %s",
+				key, val,
+			))
+			return
+		}
+	}
+
+	return
+}
+
+// ownerMarkerText returns the text of the marker of `owner`.
+func ownerMarkerText(owner string) string {
+	return fmt.Sprintf("%s,owner=%s", ownerMarkerHeritage, owner)
+}
+
+// parseOwnerMarker returns the owner named by the content of a marker, and
+// whether it's a marker at all.
+func parseOwnerMarker(content string) (string, bool) {
+	text, err := dnsrecord.ParseTXT(content)
+	if err != nil {
+		text = content
+	}
+
+	fields := strings.Split(text, ",")
+	if fields[0] != ownerMarkerHeritage {
+		return "", false
+	}
+
+	for _, field := range fields[1:] {
+		if owner := strings.TrimPrefix(field, "owner="); owner != field {
+			return owner, true
+		}
+	}
+
+	return "", true
+}
+
+// findOwnerMarker returns the marker of the records of `recordType` at
+// `name` among `records`, or nil if there's none.
+func findOwnerMarker(
+	records []gonjalla.Record, domain string, recordType string, name string,
+) *gonjalla.Record {
+	markerName := ownerMarkerName(recordType, name)
+
+	for i, record := range records {
+		if record.Type != "TXT" {
+			continue
+		}
+
+		if !strings.EqualFold(dnsrecord.NormalizeName(record.Name, domain), markerName) {
+			continue
+		}
+
+		if _, ok := parseOwnerMarker(record.Content); ok {
+			return &records[i]
+		}
+	}
+
+	return nil
+}
+
+// checkOwnership returns an error diagnostic if the records of `recordType`
+// at `name` aren't owned by the provider's owner: either their marker names
+// another owner, or there are records without a marker and the provider
+// isn't configured with `claim_unmarked_records`.
+func (c *Config) checkOwnership(
+	records []gonjalla.Record,
+	domain string,
+	recordType string,
+	name string,
+	operation string,
+	resource string,
+) diag.Diagnostics {
+	marker := findOwnerMarker(records, domain, recordType, name)
+	if marker != nil {
+		owner, _ := parseOwnerMarker(marker.Content)
+		if owner == c.OwnerID {
+			return nil
+		}
+
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Records owned by another owner for %s", resource),
+				Detail: fmt.Sprintf(
+					"Refusing to %s %s records at %s in domain %s, as their "+
+						"ownership marker %s names the owner %q instead of "+
+						"this provider's owner_id %q.",
+					operation, recordType, name, domain,
+					ownerMarkerName(recordType, name), owner, c.OwnerID,
+				),
+			},
+		}
+	}
+
+	if c.ClaimUnmarked {
+		return nil
+	}
+
+	var unmarked []string
+	for _, record := range records {
+		if record.Type == recordType &&
+			strings.EqualFold(dnsrecord.NormalizeName(record.Name, domain), name) {
+			unmarked = append(unmarked, record.ID)
+		}
+	}
+	if len(unmarked) == 0 {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Records without an owner for %s", resource),
+			Detail: fmt.Sprintf(
+				"Refusing to %s %s records at %s in domain %s, as the "+
+					"existing records %s have no ownership marker %s, so "+
+					"they may be managed elsewhere. Set "+
+					"claim_unmarked_records = true in the provider to claim "+
+					"them for owner_id %q.",
+				operation, recordType, name, domain,
+				strings.Join(unmarked, ", "),
+				ownerMarkerName(recordType, name), c.OwnerID,
+			),
+		},
+	}
+}
+
+// claimOwnership adds the marker of the records of `recordType` at `name`
+// if there's none among `records`.
+func (c *Config) claimOwnership(
+	ctx context.Context,
+	records []gonjalla.Record,
+	domain string,
+	recordType string,
+	name string,
+) error {
+	if findOwnerMarker(records, domain, recordType, name) != nil {
+		return nil
+	}
+
+	_, err := c.addRecord(ctx, domain, gonjalla.Record{
+		Type:    "TXT",
+		Name:    ownerMarkerName(recordType, name),
+		Content: dnsrecord.FormatTXT(ownerMarkerText(c.OwnerID)),
+		TTL:     c.defaultTTL(),
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"Adding the ownership marker of %s records at %s failed: %w",
+			recordType, name, err,
+		)
+	}
+
+	return nil
+}
+
+// releaseOwnership removes the marker of the records of `recordType` at
+// `name` once none are left, other than the one with ID `removedID`. Only
+// markers naming the provider's owner are removed.
+func (c *Config) releaseOwnership(
+	ctx context.Context,
+	domain string,
+	recordType string,
+	name string,
+	removedID string,
+) error {
+	records, err := c.listRecords(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.ID != removedID && record.Type == recordType &&
+			strings.EqualFold(dnsrecord.NormalizeName(record.Name, domain), name) {
+			return nil
+		}
+	}
+
+	marker := findOwnerMarker(records, domain, recordType, name)
+	if marker == nil {
+		return nil
+	}
+
+	if owner, _ := parseOwnerMarker(marker.Content); owner != c.OwnerID {
+		return nil
+	}
+
+	if err := c.removeRecord(ctx, domain, marker.ID); err != nil {
+		return fmt.Errorf(
+			"Removing the ownership marker of %s records at %s failed: %w",
+			recordType, name, err,
+		)
+	}
+
+	return nil
+}
+
+// updateRecord edits a record, refusing to if its records are owned by
+// another owner, before or after a change of name, or if it's renamed away
+// from a protected name. The marker of its new name is claimed, and the
+// marker of its old one released. With `cname_conflicts = "wait"`, a renamed
+// record first waits for conflicting records to be removed.
+func (c *Config) updateRecord(
+	ctx context.Context,
+	d *schema.ResourceData,
+	domain string,
+	record gonjalla.Record,
+	resource string,
+) diag.Diagnostics {
+	oldName, _ := d.GetChange("name")
+	previous := dnsrecord.NormalizeName(oldName.(string), domain)
+	name := dnsrecord.NormalizeName(record.Name, domain)
+
+	names := []string{previous}
+	if !strings.EqualFold(previous, name) {
+		// Renaming a protected record away removes it from its name, which
+		// is as disruptive as deleting it.
+		if diags := c.checkDeletable(domain, record.Type, previous, resource); diags != nil {
+			return diags
+		}
+		names = append(names, name)
+	}
+
+	var diags diag.Diagnostics
+	if d.HasChange("name") {
+		diags = c.waitForCNAMEConflicts(
+			ctx, domain, record.Type, name, record.ID, "update", resource,
+		)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	if c.OwnerID == "" {
+		if err := c.editRecord(ctx, domain, record); err != nil {
+			return append(diags, diagFromAPIError(err, "update", resource)...)
+		}
+
+		return diags
+	}
+
+	records, err := c.listRecords(ctx, domain)
+	if err != nil {
+		return append(diags, diagFromAPIError(err, "update", resource)...)
+	}
+
+	for _, n := range names {
+		ownerDiags := c.checkOwnership(records, domain, record.Type, n, "update", resource)
+		if ownerDiags.HasError() {
+			return append(diags, ownerDiags...)
+		}
+	}
+
+	if err := c.editRecord(ctx, domain, record); err != nil {
+		return append(diags, diagFromAPIError(err, "update", resource)...)
+	}
+
+	if err := c.claimOwnership(ctx, records, domain, record.Type, name); err != nil {
+		return append(diags, diagFromAPIError(err, "update", resource)...)
+	}
+
+	if !strings.EqualFold(previous, name) {
+		if err := c.releaseOwnership(ctx, domain, record.Type, previous, ""); err != nil {
+			return append(diags, diagFromAPIError(err, "update", resource)...)
+		}
+	}
+
+	return diags
+}
+
+// deleteRecord removes the record described by `d`, refusing to if it's
+// protected or its records are owned by another owner. Its marker is
+// released.
+func (c *Config) deleteRecord(
+	ctx context.Context,
+	d *schema.ResourceData,
+	domain string,
+	recordType string,
+	resource string,
+) diag.Diagnostics {
+	name := recordName(d)
+
+	if diags := c.checkDeletable(domain, recordType, name, resource); diags != nil {
+		return diags
+	}
+
+	if c.OwnerID == "" {
+		if err := c.removeRecord(ctx, domain, d.Id()); err != nil {
+			return diagFromAPIError(err, "delete", resource)
+		}
+
+		return nil
+	}
+
+	records, err := c.listRecords(ctx, domain)
+	if err != nil {
+		return diagFromAPIError(err, "delete", resource)
+	}
+
+	diags := c.checkOwnership(records, domain, recordType, name, "delete", resource)
+	if diags.HasError() {
+		return diags
+	}
+
+	if err := c.removeRecord(ctx, domain, d.Id()); err != nil {
+		return diagFromAPIError(err, "delete", resource)
+	}
+
+	if err := c.releaseOwnership(ctx, domain, recordType, name, d.Id()); err != nil {
+		return diagFromAPIError(err, "delete", resource)
+	}
+
+	return nil
+}
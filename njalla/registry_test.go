@@ -0,0 +1,266 @@
+package njalla
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+// newTestRegistry returns a config with the ownership registry enabled for
+// `owner`, against a fake API with the domain `testing.com`.
+func newTestRegistry(t *testing.T, owner string) (*Config, *njallatest.Server) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	return &Config{Client: client, OwnerID: owner}, server
+}
+
+// testRegistryMarkers returns the owners named by the markers of
+// `testing.com`, by marker name.
+func testRegistryMarkers(server *njallatest.Server) map[string]string {
+	markers := map[string]string{}
+	for _, record := range server.Records("testing.com") {
+		if owner, ok := parseOwnerMarker(record.Content); ok {
+			markers[record.Name] = owner
+		}
+	}
+
+	return markers
+}
+
+func TestOwnerMarkerName(t *testing.T) {
+	cases := []struct {
+		recordType string
+		name       string
+		expected   string
+	}{
+		{"A", "@", "_njalla-owner-a"},
+		{"A", "www", "_njalla-owner-a.www"},
+		{"CNAME", "www.dev", "_njalla-owner-cname.www.dev"},
+		{"TXT", "*", "_njalla-owner-txt._wildcard"},
+		{"TXT", "*.dev", "_njalla-owner-txt._wildcard.dev"},
+	}
+
+	for _, c := range cases {
+		if name := ownerMarkerName(c.recordType, c.name); name != c.expected {
+			t.Errorf(
+				"ownerMarkerName(%s, %s) = %s, expected %s",
+				c.recordType, c.name, name, c.expected,
+			)
+		}
+	}
+}
+
+func TestParseOwnerMarker(t *testing.T) {
+	cases := []struct {
+		content  string
+		owner    string
+		isMarker bool
+	}{
+		{`"heritage=terraform-provider-njalla,owner=team-a"`, "team-a", true},
+		{"heritage=terraform-provider-njalla,owner=team-a", "team-a", true},
+		{"heritage=terraform-provider-njalla", "", true},
+		{`"heritage=external-dns,external-dns/owner=default"`, "", false},
+		{"v=spf1 -all", "", false},
+	}
+
+	for _, c := range cases {
+		owner, isMarker := parseOwnerMarker(c.content)
+		if owner != c.owner || isMarker != c.isMarker {
+			t.Errorf(
+				"parseOwnerMarker(%q) = %q, %t, expected %q, %t",
+				c.content, owner, isMarker, c.owner, c.isMarker,
+			)
+		}
+	}
+}
+
+func TestRegistryLifecycle(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	ctx := context.Background()
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "10800",
+			"content": "1.1.1.1",
+		},
+	)
+
+	if diags := resourceRecordACreate(ctx, d, config); diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+
+	markers := testRegistryMarkers(server)
+	if markers["_njalla-owner-a.www"] != "team-a" {
+		t.Fatalf("Expected a marker for team-a, got %v", markers)
+	}
+
+	if diags := resourceRecordAUpdate(ctx, d, config); diags.HasError() {
+		t.Fatalf("Update failed: %v", diags)
+	}
+
+	if diags := resourceRecordADelete(ctx, d, config); diags.HasError() {
+		t.Fatalf("Delete failed: %v", diags)
+	}
+
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected the record and its marker removed, got %v", records)
+	}
+}
+
+func TestRegistryKeepsSharedMarker(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	ctx := context.Background()
+
+	var resources []*schema.ResourceData
+	for _, content := range []string{"1.1.1.1", "1.1.1.2"} {
+		d := schema.TestResourceDataRaw(
+			t, resourceRecordA().Schema, map[string]interface{}{
+				"domain":  "testing.com",
+				"name":    "www",
+				"ttl":     "10800",
+				"content": content,
+			},
+		)
+		if diags := resourceRecordACreate(ctx, d, config); diags.HasError() {
+			t.Fatalf("Create failed: %v", diags)
+		}
+		resources = append(resources, d)
+	}
+
+	if markers := testRegistryMarkers(server); len(markers) != 1 {
+		t.Fatalf("Expected a single marker, got %v", markers)
+	}
+
+	if diags := resourceRecordADelete(ctx, resources[0], config); diags.HasError() {
+		t.Fatalf("Delete failed: %v", diags)
+	}
+
+	if markers := testRegistryMarkers(server); len(markers) != 1 {
+		t.Fatalf("Expected the marker kept for the other record, got %v", markers)
+	}
+}
+
+func TestRegistryRefusesForeignRecords(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	ctx := context.Background()
+
+	record := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 10800,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type:    "TXT",
+		Name:    "_njalla-owner-a.www",
+		Content: dnsrecord.FormatTXT(ownerMarkerText("team-b")),
+		TTL:     10800,
+	})
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "10800",
+			"content": "1.1.1.2",
+		},
+	)
+
+	diags := resourceRecordACreate(ctx, d, config)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, `"team-b"`) {
+		t.Fatalf("Expected Create to be refused, got %v", diags)
+	}
+
+	d.SetId(record.ID)
+
+	if diags := resourceRecordAUpdate(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Update to be refused")
+	}
+	if diags := resourceRecordADelete(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Delete to be refused")
+	}
+
+	if records := server.Records("testing.com"); len(records) != 2 ||
+		records[0].Content != "1.1.1.1" {
+		t.Fatalf("Expected the foreign records untouched, got %v", records)
+	}
+}
+
+func TestRegistryRefusesUnmarkedRecords(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	ctx := context.Background()
+
+	record := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 10800,
+	})
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "3600",
+			"content": "1.1.1.2",
+		},
+	)
+
+	// Adding a record next to it would claim it along with the new one.
+	diags := resourceRecordACreate(ctx, d, config)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "claim_unmarked_records") {
+		t.Fatalf("Expected Create to be refused, got %v", diags)
+	}
+
+	d.SetId(record.ID)
+
+	if diags := resourceRecordAUpdate(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Update to be refused")
+	}
+	if diags := resourceRecordADelete(ctx, d, config); !diags.HasError() {
+		t.Fatal("Expected Delete to be refused")
+	}
+
+	records := server.Records("testing.com")
+	if len(records) != 1 || records[0] != record {
+		t.Fatalf("Expected the unmarked record untouched, got %v", records)
+	}
+}
+
+func TestRegistryClaimsUnmarkedRecords(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	config.ClaimUnmarked = true
+	ctx := context.Background()
+
+	record := server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.1", TTL: 10800,
+	})
+
+	d := schema.TestResourceDataRaw(
+		t, resourceRecordA().Schema, map[string]interface{}{
+			"domain":  "testing.com",
+			"name":    "www",
+			"ttl":     "3600",
+			"content": "1.1.1.1",
+		},
+	)
+	d.SetId(record.ID)
+
+	if diags := resourceRecordAUpdate(ctx, d, config); diags.HasError() {
+		t.Fatalf("Update failed: %v", diags)
+	}
+
+	markers := testRegistryMarkers(server)
+	if markers["_njalla-owner-a.www"] != "team-a" {
+		t.Fatalf("Expected the record claimed by team-a, got %v", markers)
+	}
+}
@@ -0,0 +1,255 @@
+package njalla
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/acme"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// The challenge record is found and removed through the `acme` package, so
+// it's named and encoded exactly like with a lego client using it. It's
+// added like any other record, through `createRecord`, so adoption,
+// ownership markers and CNAME conflicts are handled the same way.
+
+func resourceACMEChallenge() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceACMEChallengeCreate,
+		ReadContext:   resourceACMEChallengeRead,
+		UpdateContext: resourceACMEChallengeUpdate,
+		DeleteContext: resourceACMEChallengeDelete,
+
+		Schema: map[string]*schema.Schema{
+			"domain": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				Description: "Domain being validated, like www.example.com " +
+					"or *.example.com.",
+				ValidateFunc:     stringValidator(validateChallengeDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
+			},
+			"key_authorization": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Sensitive:   true,
+				Description: "Key authorization of the DNS-01 challenge.",
+			},
+			"zone": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+				Description: "Njalla domain to add the record to. Defaults " +
+					"to the longest suffix of domain in the account.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
+			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
+			"name": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Name of the record, relative to zone.",
+			},
+			"fqdn": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Fully qualified name of the record.",
+			},
+			"value": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Text of the record.",
+			},
+		},
+
+		CustomizeDiff: customizeRecordTTL,
+
+		Timeouts: recordTimeouts(),
+	}
+}
+
+// validateChallengeDomain checks a domain to validate, which may be a
+// wildcard.
+func validateChallengeDomain(domain string) error {
+	return validateDomain(strings.TrimPrefix(domain, "*."))
+}
+
+// acmeProvider returns the challenge provider for `d`, calling the API
+// through the provider's client.
+func (c *Config) acmeProvider(d *schema.ResourceData, ttl int) *acme.DNSProvider {
+	return acme.NewDNSProviderClient(c.Client, &acme.Config{
+		Zone: d.Get("zone").(string),
+		TTL:  ttl,
+	})
+}
+
+func resourceACMEChallengeCreate(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_acme_challenge"); diags != nil {
+		return diags
+	}
+
+	ttl, diags := config.recordTTL(d, "njalla_acme_challenge")
+	if diags.HasError() {
+		return diags
+	}
+
+	challenge, err := config.acmeProvider(d, ttl).Challenge(
+		ctx, d.Get("domain").(string), d.Get("key_authorization").(string),
+	)
+	if err != nil {
+		return append(diags, diagFromAPIError(err, "create", "njalla_acme_challenge")...)
+	}
+
+	record := gonjalla.Record{
+		Type:    "TXT",
+		Name:    challenge.Name,
+		Content: dnsrecord.FormatTXT(challenge.Value),
+		TTL:     ttl,
+	}
+
+	saved, createDiags := config.createRecord(
+		ctx, d, challenge.Zone, record, "njalla_acme_challenge",
+	)
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
+	}
+	challenge.Record = saved
+
+	d.SetId(saved.ID)
+	d.Set("zone", challenge.Zone)
+	d.Set("name", challenge.Name)
+	d.Set("fqdn", challenge.FQDN)
+	d.Set("value", challenge.Value)
+
+	diags = append(diags, waitForPropagation(ctx, d, challenge.Zone, challenge.Record, "njalla_acme_challenge")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceACMEChallengeRead(ctx, d, m)...)
+}
+
+func resourceACMEChallengeRead(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+
+	zone := d.Get("zone").(string)
+
+	var diags diag.Diagnostics
+
+	records, err := config.listRecords(ctx, zone)
+	if err != nil {
+		return diagFromAPIError(err, "read", "njalla_acme_challenge")
+	}
+
+	for _, record := range records {
+		if d.Id() == record.ID {
+			setRecordTTL(d, config, record.TTL)
+
+			return diags
+		}
+	}
+
+	d.SetId("")
+	return diags
+}
+
+func resourceACMEChallengeUpdate(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceACMEChallengeRead(ctx, d, m)
+	}
+
+	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_acme_challenge"); diags != nil {
+		return diags
+	}
+
+	zone := d.Get("zone").(string)
+
+	ttl, diags := config.recordTTL(d, "njalla_acme_challenge")
+	if diags.HasError() {
+		return diags
+	}
+
+	updateRecord := gonjalla.Record{
+		ID:      d.Id(),
+		Name:    d.Get("name").(string),
+		Type:    "TXT",
+		Content: dnsrecord.FormatTXT(d.Get("value").(string)),
+		TTL:     ttl,
+	}
+
+	updateDiags := config.updateRecord(ctx, d, zone, updateRecord, "njalla_acme_challenge")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, zone, updateRecord, "njalla_acme_challenge")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceACMEChallengeRead(ctx, d, m)...)
+}
+
+func resourceACMEChallengeDelete(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_acme_challenge"); diags != nil {
+		return diags
+	}
+
+	zone := d.Get("zone").(string)
+	name := d.Get("name").(string)
+
+	if diags := config.checkDeletable(zone, "TXT", name, "njalla_acme_challenge"); diags != nil {
+		return diags
+	}
+
+	if config.OwnerID != "" {
+		records, err := config.listRecords(ctx, zone)
+		if err != nil {
+			return diagFromAPIError(err, "delete", "njalla_acme_challenge")
+		}
+
+		diags := config.checkOwnership(
+			records, zone, "TXT", name, "delete", "njalla_acme_challenge",
+		)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	err := config.acmeProvider(d, 0).CleanUpContext(
+		ctx, d.Get("domain").(string), d.Get("key_authorization").(string),
+	)
+	if err != nil {
+		return diagFromAPIError(err, "delete", "njalla_acme_challenge")
+	}
+
+	if config.OwnerID != "" {
+		if err := config.releaseOwnership(ctx, zone, "TXT", name, ""); err != nil {
+			return diagFromAPIError(err, "delete", "njalla_acme_challenge")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/acme"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+func TestAccACMEChallenge_Create(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	_, value := acme.ChallengeRecord(
+		"testacc1-acme-name."+domain, "testacc1-acme-key-authorization",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckACMEChallengeDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckACMEChallengeCreate(60),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"njalla_acme_challenge.test", "zone", domain,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_acme_challenge.test",
+						"name",
+						"_acme-challenge.testacc1-acme-name",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_acme_challenge.test",
+						"fqdn",
+						"_acme-challenge.testacc1-acme-name."+domain+".",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_acme_challenge.test", "value", value,
+					),
+					testAccCheckACMEChallengeRecord(
+						"njalla_acme_challenge.test", value, 60,
+					),
+				),
+			},
+			{
+				// Changing the TTL edits the record in place.
+				Config: testAccCheckACMEChallengeCreate(300),
+				Check: testAccCheckACMEChallengeRecord(
+					"njalla_acme_challenge.test", value, 300,
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckACMEChallengeRecord checks the challenge's record exists, with
+// the expected text and TTL.
+func testAccCheckACMEChallengeRecord(
+	resource string, value string, ttl int,
+) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		rs, ok := state.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resource)
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		zone := rs.Primary.Attributes["zone"]
+		records, err := config.Client.ListRecords(context.Background(), zone)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s", zone, err,
+			)
+		}
+
+		for _, record := range records {
+			if record.ID != rs.Primary.ID {
+				continue
+			}
+
+			text, _ := dnsrecord.ParseTXT(record.Content)
+			if record.Type != "TXT" || text != value || record.TTL != ttl {
+				return fmt.Errorf(
+					"Record %s is %s %q with TTL %d, expected TXT %q with "+
+						"TTL %d",
+					record.ID, record.Type, text, record.TTL, value, ttl,
+				)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf(
+			"Record %s doesn't exist for domain %s", rs.Primary.ID, zone,
+		)
+	}
+}
+
+func testAccCheckACMEChallengeDestroy(s *terraform.State) error {
+	config := testAccProvider.Meta().(*Config)
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "njalla_acme_challenge" {
+			continue
+		}
+
+		zone := rs.Primary.Attributes["zone"]
+		records, err := config.Client.ListRecords(context.Background(), zone)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s", zone, err,
+			)
+		}
+
+		for _, record := range records {
+			if record.ID == rs.Primary.ID {
+				return fmt.Errorf(
+					"Record %s still exists in domain %s", rs.Primary.ID, zone,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+func testAccCheckACMEChallengeCreate(ttl int) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_acme_challenge test {
+  domain = "testacc1-acme-name.%s"
+  key_authorization = "testacc1-acme-key-authorization"
+  ttl = %d
+}
+`, domain, ttl)
+}
+
+// testACMEChallengeData returns the data of a challenge for
+// `www.testing.com`, along with the text of its record.
+func testACMEChallengeData(t *testing.T) (*schema.ResourceData, string) {
+	_, value := acme.ChallengeRecord("www.testing.com", "key-authorization")
+
+	return schema.TestResourceDataRaw(
+		t, resourceACMEChallenge().Schema, map[string]interface{}{
+			"domain":            "www.testing.com",
+			"key_authorization": "key-authorization",
+			"ttl":               "60",
+		},
+	), value
+}
+
+func TestACMEChallengeCreateOwnership(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	d, value := testACMEChallengeData(t)
+
+	ctx := context.Background()
+	if diags := resourceACMEChallengeCreate(ctx, d, config); diags.HasError() {
+		t.Fatalf("Create failed: %v", diags)
+	}
+
+	markers := testRegistryMarkers(server)
+	if markers["_njalla-owner-txt._acme-challenge.www"] != "team-a" {
+		t.Fatalf("Expected the challenge claimed for team-a, got %v", markers)
+	}
+	if d.Get("value").(string) != value {
+		t.Fatalf("Unexpected value %q", d.Get("value"))
+	}
+
+	if diags := resourceACMEChallengeDelete(ctx, d, config); diags.HasError() {
+		t.Fatalf("Delete failed: %v", diags)
+	}
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected the record and its marker removed, got %v", records)
+	}
+}
+
+func TestACMEChallengeCreateForeign(t *testing.T) {
+	config, server := newTestRegistry(t, "team-a")
+	d, value := testACMEChallengeData(t)
+	config.AdoptExisting = true
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type:    "TXT",
+		Name:    "_acme-challenge.www",
+		Content: dnsrecord.FormatTXT(value),
+		TTL:     60,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type:    "TXT",
+		Name:    "_njalla-owner-txt._acme-challenge.www",
+		Content: dnsrecord.FormatTXT(ownerMarkerText("team-b")),
+		TTL:     60,
+	})
+
+	diags := resourceACMEChallengeCreate(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Fatal("Expected Create to refuse the record of team-b")
+	}
+	if len(server.Records("testing.com")) != 2 {
+		t.Fatalf("Expected no record added, got %v", server.Records("testing.com"))
+	}
+}
+
+func TestACMEChallengeCreateReuse(t *testing.T) {
+	cases := []struct {
+		name  string
+		adopt bool
+		// records is the number of challenge records expected.
+		records int
+	}{
+		{"adopted", true, 1},
+		{"not adopted", false, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, server := newTestRegistry(t, "")
+			config.AdoptExisting = c.adopt
+			d, value := testACMEChallengeData(t)
+
+			existing := server.AddRecord("testing.com", gonjalla.Record{
+				Type:    "TXT",
+				Name:    "_acme-challenge.www",
+				Content: dnsrecord.FormatTXT(value),
+				TTL:     60,
+			})
+
+			diags := resourceACMEChallengeCreate(context.Background(), d, config)
+			if diags.HasError() {
+				t.Fatalf("Create failed: %v", diags)
+			}
+
+			if records := server.Records("testing.com"); len(records) != c.records {
+				t.Fatalf("Expected %d records, got %v", c.records, records)
+			}
+			if (d.Id() == existing.ID) != c.adopt {
+				t.Fatalf("Unexpected ID %s, existing record is %s", d.Id(), existing.ID)
+			}
+		})
+	}
+}
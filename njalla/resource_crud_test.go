@@ -0,0 +1,205 @@
+package njalla
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+// The tests in this file call the CRUD functions of every record resource
+// directly against the fake Njalla API. Unlike the acceptance tests they
+// don't need a Terraform CLI, so they always run.
+
+// newTestCRUDConfig returns a config against a fake API with the domain
+// `testing.com`.
+func newTestCRUDConfig(t *testing.T) (*Config, *njallatest.Server) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	return &Config{Client: client}, server
+}
+
+func TestRecordResourcesCRUD(t *testing.T) {
+	const tlsaData = "0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b566" +
+		"64c5d3d6"
+
+	cases := []struct {
+		name     string
+		resource *schema.Resource
+		create   map[string]interface{}
+		update   map[string]interface{}
+		// content is the content expected in the fake API after Create and
+		// after Update.
+		content [2]string
+	}{
+		{
+			name:     "njalla_record_a",
+			resource: resourceRecordA(),
+			create:   map[string]interface{}{"content": "1.1.1.1"},
+			update:   map[string]interface{}{"content": "2.2.2.2"},
+			content:  [2]string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			name:     "njalla_record_aaaa",
+			resource: resourceRecordAAAA(),
+			create:   map[string]interface{}{"content": "2001:db8::1"},
+			update:   map[string]interface{}{"content": "2001:db8::2"},
+			content:  [2]string{"2001:db8::1", "2001:db8::2"},
+		},
+		{
+			name:     "njalla_record_caa",
+			resource: resourceRecordCAA(),
+			create: map[string]interface{}{
+				"content": `0 issue "letsencrypt.org"`,
+			},
+			update: map[string]interface{}{
+				"content": `0 issue "sectigo.com"`,
+			},
+			content: [2]string{
+				`0 issue "letsencrypt.org"`, `0 issue "sectigo.com"`,
+			},
+		},
+		{
+			name:     "njalla_record_cname",
+			resource: resourceRecordCNAME(),
+			create:   map[string]interface{}{"content": "one.testing.com."},
+			update:   map[string]interface{}{"content": "two.testing.com."},
+			content:  [2]string{"one.testing.com.", "two.testing.com."},
+		},
+		{
+			name:     "njalla_record_mx",
+			resource: resourceRecordMX(),
+			create: map[string]interface{}{
+				"content": "mail1.testing.com.", "priority": 10,
+			},
+			update: map[string]interface{}{
+				"content": "mail2.testing.com.", "priority": 20,
+			},
+			content: [2]string{"mail1.testing.com.", "mail2.testing.com."},
+		},
+		{
+			name:     "njalla_record_naptr",
+			resource: resourceRecordNAPTR(),
+			create: map[string]interface{}{
+				"content": `100 10 "u" "E2U+sip" "!^.*$!sip:one@testing.com!" .`,
+			},
+			update: map[string]interface{}{
+				"content": `100 10 "u" "E2U+sip" "!^.*$!sip:two@testing.com!" .`,
+			},
+			content: [2]string{
+				`100 10 "u" "E2U+sip" "!^.*$!sip:one@testing.com!" .`,
+				`100 10 "u" "E2U+sip" "!^.*$!sip:two@testing.com!" .`,
+			},
+		},
+		{
+			name:     "njalla_record_ns",
+			resource: resourceRecordNS(),
+			create:   map[string]interface{}{"content": "ns1.testing.com."},
+			update:   map[string]interface{}{"content": "ns2.testing.com."},
+			content:  [2]string{"ns1.testing.com.", "ns2.testing.com."},
+		},
+		{
+			name:     "njalla_record_ptr",
+			resource: resourceRecordPTR(),
+			create:   map[string]interface{}{"content": "one.testing.com."},
+			update:   map[string]interface{}{"content": "two.testing.com."},
+			content:  [2]string{"one.testing.com.", "two.testing.com."},
+		},
+		{
+			name:     "njalla_record_tlsa",
+			resource: resourceRecordTLSA(),
+			create:   map[string]interface{}{"content": "3 1 1 " + tlsaData},
+			update:   map[string]interface{}{"content": "2 1 1 " + tlsaData},
+			content:  [2]string{"3 1 1 " + tlsaData, "2 1 1 " + tlsaData},
+		},
+		{
+			name:     "njalla_record_txt",
+			resource: resourceRecordTXT(),
+			create:   map[string]interface{}{"content": "hello"},
+			update:   map[string]interface{}{"content": "world"},
+			content:  [2]string{"hello", "world"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, server := newTestCRUDConfig(t)
+			ctx := context.Background()
+
+			raw := func(values map[string]interface{}) map[string]interface{} {
+				full := map[string]interface{}{
+					"domain": "testing.com",
+					"name":   "crud",
+					"ttl":    "3600",
+				}
+				for k, v := range values {
+					full[k] = v
+				}
+				return full
+			}
+
+			expectContent := func(step string, content string) {
+				t.Helper()
+
+				records := server.Records("testing.com")
+				if len(records) != 1 {
+					t.Fatalf("%s: expected one record, got %v", step, records)
+				}
+				if records[0].Content != content {
+					t.Fatalf(
+						"%s: expected content %q, got %q",
+						step, content, records[0].Content,
+					)
+				}
+			}
+
+			d := schema.TestResourceDataRaw(t, c.resource.Schema, raw(c.create))
+			if diags := c.resource.CreateContext(ctx, d, config); diags.HasError() {
+				t.Fatalf("Create failed: %v", diags)
+			}
+			if d.Id() == "" {
+				t.Fatal("Create didn't set an ID")
+			}
+			expectContent("Create", c.content[0])
+
+			if diags := c.resource.ReadContext(ctx, d, config); diags.HasError() {
+				t.Fatalf("Read failed: %v", diags)
+			}
+			if d.Id() == "" || d.Get("ttl").(string) != "3600" {
+				t.Fatalf("Unexpected state after Read: %v", d.State())
+			}
+
+			updated := schema.TestResourceDataRaw(
+				t, c.resource.Schema, raw(c.update),
+			)
+			updated.SetId(d.Id())
+			if diags := c.resource.UpdateContext(ctx, updated, config); diags.HasError() {
+				t.Fatalf("Update failed: %v", diags)
+			}
+			expectContent("Update", c.content[1])
+
+			if diags := c.resource.DeleteContext(ctx, updated, config); diags.HasError() {
+				t.Fatalf("Delete failed: %v", diags)
+			}
+			if records := server.Records("testing.com"); len(records) != 0 {
+				t.Fatalf("Expected the record deleted, got %v", records)
+			}
+
+			// Reading a deleted record clears it from the state.
+			if diags := c.resource.ReadContext(ctx, updated, config); diags.HasError() {
+				t.Fatalf("Read failed: %v", diags)
+			}
+			if updated.Id() != "" {
+				t.Fatalf("Expected the ID cleared, got %q", updated.Id())
+			}
+		})
+	}
+}
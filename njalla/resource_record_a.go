@@ -5,14 +5,14 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
 )
 
 func resourceRecordA() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordACreate,
 		ReadContext:   resourceRecordARead,
 		UpdateContext: resourceRecordAUpdate,
@@ -20,10 +20,12 @@ func resourceRecordA() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -31,50 +33,71 @@ func resourceRecordA() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
 				Type:         schema.TypeString,
 				Required:     true,
 				Description:  "IPv4 address for the record.",
-				ValidateFunc: validation.IsIPv4Address,
+				ValidateFunc: validateContent("A"),
 			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("A"),
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordAImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordACreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_a"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_a")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "A",
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_a")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(saved.ID)
 
-	return resourceRecordARead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_a")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordARead(ctx, d, m)...)
 
 }
 
@@ -87,15 +110,15 @@ func resourceRecordARead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_a")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("content", record.Content)
 
 			return diags
@@ -109,40 +132,55 @@ func resourceRecordARead(
 func resourceRecordAUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordARead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_a"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_a")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "A",
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_a")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_a")...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordARead(ctx, d, m)
+	return append(diags, resourceRecordARead(ctx, d, m)...)
 }
 
 func resourceRecordADelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_a"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "A", "njalla_record_a")
 }
 
 func resourceRecordAImport(
@@ -155,7 +193,7 @@ func resourceRecordAImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -166,8 +204,8 @@ func resourceRecordAImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("content", record.Content)
 
 			return []*schema.ResourceData{d}, nil
@@ -1,17 +1,26 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
 	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_a", &resource.Sweeper{
+		Name: "njalla_record_a",
+		F:    testSweepRecords("A"),
+	})
+}
+
 func TestAccRecordA_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -203,6 +212,205 @@ func TestAccRecordA_InvalidContent(t *testing.T) {
 	})
 }
 
+func TestAccRecordA_FullyQualifiedName(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	fqdn := fmt.Sprintf("testacc7-a-fqdn-name.%s.", domain)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordAFullyQualifiedName(fqdn),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordAExists("njalla_record_a.test_fqdn"),
+					testAccCheckRecordAName(
+						"njalla_record_a.test_fqdn", "testacc7-a-fqdn-name",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_a.test_fqdn", "name", fqdn,
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordAFullyQualifiedName(
+					"testacc7-a-fqdn-name",
+				),
+				PlanOnly: true,
+			},
+			{
+				ResourceName:            "njalla_record_a.test_fqdn",
+				ImportStateIdPrefix:     fmt.Sprintf("%s:", domain),
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"name"},
+			},
+		},
+	})
+}
+
+func TestAccRecordA_InvalidName(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected name to be a valid record name, got: testacc8-a-\\*-name",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordAInvalidName(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordA_DurationTTL(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordADurationTTL("1h"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordAExists("njalla_record_a.test_duration"),
+					testAccCheckRecordATTL(
+						"njalla_record_a.test_duration", 3600,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_a.test_duration", "ttl", "1h",
+					),
+				),
+			},
+			{
+				Config:   testAccCheckRecordADurationTTL("3600"),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccRecordA_DefaultTTL(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordADefaultTTL(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordAExists("njalla_record_a.test_default_ttl"),
+					testAccCheckRecordATTL(
+						"njalla_record_a.test_default_ttl", 10800,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_a.test_default_ttl", "ttl", "10800",
+					),
+				),
+			},
+		},
+	})
+}
+
+// TestAccRecordA_RoundedTTL configures the shared provider differently, so it
+// can't run in parallel with other tests. Its configuration has a provider
+// block, so it uses factories, as `Providers` always adds an empty one.
+func TestAccRecordA_RoundedTTL(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		ProviderFactories: map[string]func() (*schema.Provider, error){
+			"njalla": func() (*schema.Provider, error) {
+				return testAccProvider, nil
+			},
+		},
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordARoundedTTL(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordAExists("njalla_record_a.test_rounded_ttl"),
+					testAccCheckRecordATTL(
+						"njalla_record_a.test_rounded_ttl", 900,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_a.test_rounded_ttl", "ttl", "1000",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordA_CNAMEConflict(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"A record testacc12-a-conflict-name in domain .+ conflicts with " +
+			"the existing CNAME record",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if err := testAccCheckRecordADestroy(s); err != nil {
+				return err
+			}
+			return testAccCheckRecordCNAMEDestroy(s)
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordACNAMEConflict(false),
+			},
+			{
+				Config:      testAccCheckRecordACNAMEConflict(true),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordA_AdoptExisting(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	var existingID string
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordADestroy,
+		Steps: []resource.TestStep{
+			{
+				// Adds the record the resource should adopt, as if a
+				// previous apply had been interrupted.
+				PreConfig: func() {
+					saved, err := testAccAPIClient().AddRecord(
+						context.Background(), domain, gonjalla.Record{
+							Type:    "A",
+							Name:    "testacc13-a-adopt-name",
+							Content: "1.1.1.13",
+							TTL:     10800,
+						},
+					)
+					if err != nil {
+						t.Fatalf("Adding the record to adopt failed: %s", err)
+					}
+					existingID = saved.ID
+				},
+				Config: testAccCheckRecordAAdoptExisting(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPtr(
+						"njalla_record_a.test_adopt", "id", &existingID,
+					),
+					testAccCheckRecordATTL("njalla_record_a.test_adopt", 3600),
+					testAccCheckRecordACount("testacc13-a-adopt-name", 1),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckRecordADestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -212,7 +420,9 @@ func testAccCheckRecordADestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -245,7 +455,9 @@ func testAccCheckRecordAExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -265,6 +477,117 @@ func testAccCheckRecordAExists(resource string) resource.TestCheckFunc {
 	}
 }
 
+// testAccCheckRecordAName checks the name of the record stored by the API.
+func testAccCheckRecordAName(resource string, name string) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		rs, ok := state.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resource)
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		for _, record := range records {
+			if record.ID == rs.Primary.ID {
+				if record.Name != name {
+					return fmt.Errorf(
+						"Record %s has name %s, expected %s",
+						record.ID, record.Name, name,
+					)
+				}
+
+				return nil
+			}
+		}
+
+		return fmt.Errorf(
+			"Record %s doesn't exist for domain %s", rs.Primary.ID, domain,
+		)
+	}
+}
+
+// testAccCheckRecordATTL checks the TTL of the record stored by the API.
+func testAccCheckRecordATTL(resource string, ttl int) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		rs, ok := state.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resource)
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		for _, record := range records {
+			if record.ID == rs.Primary.ID {
+				if record.TTL != ttl {
+					return fmt.Errorf(
+						"Record %s has TTL %d, expected %d",
+						record.ID, record.TTL, ttl,
+					)
+				}
+
+				return nil
+			}
+		}
+
+		return fmt.Errorf(
+			"Record %s doesn't exist for domain %s", rs.Primary.ID, domain,
+		)
+	}
+}
+
+// testAccCheckRecordACount checks the number of A records the API has with
+// the given name.
+func testAccCheckRecordACount(name string, count int) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		config := testAccProvider.Meta().(*Config)
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		found := 0
+		for _, record := range records {
+			if record.Type == "A" && record.Name == name {
+				found++
+			}
+		}
+
+		if found != count {
+			return fmt.Errorf(
+				"Expected %d A records named %s, got %d", count, name, found,
+			)
+		}
+
+		return nil
+	}
+}
+
 func testAccCheckRecordACreate() string {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 	return fmt.Sprintf(`
@@ -346,3 +669,104 @@ resource njalla_record_a test_invalid_content {
   content = "testacc6-a-invalidcontent-content"
 }`, domain)
 }
+
+func testAccCheckRecordAFullyQualifiedName(name string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_fqdn {
+  domain = %q
+  name = %q
+  ttl = 10800
+  content = "1.1.1.7"
+}
+`, domain, name)
+}
+
+func testAccCheckRecordAInvalidName() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_invalid_name {
+  domain = %q
+  name = "testacc8-a-*-name"
+  ttl = 10800
+  content = "1.1.1.8"
+}
+`, domain)
+}
+
+func testAccCheckRecordADurationTTL(ttl string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_duration {
+  domain = %q
+  name = "testacc9-a-duration-name"
+  ttl = %q
+  content = "1.1.1.9"
+}
+`, domain, ttl)
+}
+
+func testAccCheckRecordADefaultTTL() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_default_ttl {
+  domain = %q
+  name = "testacc10-a-defaultttl-name"
+  content = "1.1.1.10"
+}
+`, domain)
+}
+
+func testAccCheckRecordARoundedTTL() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+provider "njalla" {
+  ttl_rounding = "nearest"
+}
+
+resource njalla_record_a test_rounded_ttl {
+  domain = %q
+  name = "testacc11-a-roundedttl-name"
+  ttl = 1000
+  content = "1.1.1.11"
+}
+`, domain)
+}
+
+func testAccCheckRecordACNAMEConflict(withA bool) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	config := fmt.Sprintf(`
+resource njalla_record_cname test_conflict {
+  domain = %q
+  name = "testacc12-a-conflict-name"
+  ttl = 10800
+  content = "testacc12-a-conflict-content.com"
+}
+`, domain)
+
+	if withA {
+		config += fmt.Sprintf(`
+resource njalla_record_a test_conflict {
+  domain = %q
+  name = "testacc12-a-conflict-name"
+  ttl = 10800
+  content = "1.1.1.12"
+}
+`, domain)
+	}
+
+	return config
+}
+
+func testAccCheckRecordAAdoptExisting() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_a test_adopt {
+  domain = %q
+  name = "testacc13-a-adopt-name"
+  ttl = 3600
+  content = "1.1.1.13"
+  adopt_existing = true
+}
+`, domain)
+}
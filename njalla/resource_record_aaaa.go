@@ -5,14 +5,14 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
 )
 
 func resourceRecordAAAA() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordAAAACreate,
 		ReadContext:   resourceRecordAAAARead,
 		UpdateContext: resourceRecordAAAAUpdate,
@@ -20,10 +20,12 @@ func resourceRecordAAAA() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -31,50 +33,71 @@ func resourceRecordAAAA() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
 				Type:         schema.TypeString,
 				Required:     true,
 				Description:  "IPv6 address for the record.",
-				ValidateFunc: validation.IsIPv6Address,
+				ValidateFunc: validateContent("AAAA"),
 			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("AAAA"),
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordAAAAImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordAAAACreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_aaaa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_aaaa")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "AAAA",
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_aaaa")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(saved.ID)
 
-	return resourceRecordAAAARead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_aaaa")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordAAAARead(ctx, d, m)...)
 }
 
 func resourceRecordAAAARead(
@@ -86,15 +109,15 @@ func resourceRecordAAAARead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_aaaa")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("content", record.Content)
 
 			return diags
@@ -108,40 +131,55 @@ func resourceRecordAAAARead(
 func resourceRecordAAAAUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordAAAARead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_aaaa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_aaaa")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "AAAA",
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_aaaa")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_aaaa")...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordAAAARead(ctx, d, m)
+	return append(diags, resourceRecordAAAARead(ctx, d, m)...)
 }
 
 func resourceRecordAAAADelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_aaaa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "AAAA", "njalla_record_aaaa")
 }
 
 func resourceRecordAAAAImport(
@@ -154,7 +192,7 @@ func resourceRecordAAAAImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -165,8 +203,8 @@ func resourceRecordAAAAImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("content", record.Content)
 
 			return []*schema.ResourceData{d}, nil
@@ -1,6 +1,7 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,15 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_aaaa", &resource.Sweeper{
+		Name: "njalla_record_aaaa",
+		F:    testSweepRecords("AAAA"),
+	})
+}
+
 func TestAccRecordAAAA_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -212,7 +218,9 @@ func testAccCheckRecordAAAADestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -245,7 +253,9 @@ func testAccCheckRecordAAAAExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -3,22 +3,19 @@ package njalla
 import (
 	"context"
 	"fmt"
-	"regexp"
-	"strconv"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
 func resourceRecordCAA() *schema.Resource {
-	contentRegex := regexp.MustCompile(
-		`^\d{1,3}\s+(?:issue|iodef|issuewild)\s+.+$`,
-	)
-
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordCAACreate,
 		ReadContext:   resourceRecordCAARead,
 		UpdateContext: resourceRecordCAAUpdate,
@@ -26,10 +23,12 @@ func resourceRecordCAA() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -37,87 +36,101 @@ func resourceRecordCAA() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Content for the record.",
-				ValidateFunc: validation.All(
-					validation.StringMatch(
-						contentRegex,
-						"value must follow RFC 8659: point 4 for syntax",
-					),
-					func(val interface{}, key string) (warns []string, errs []error) {
-						v := val.(string)
-						r := regexp.MustCompile(`^(\d{1,3})\s+`)
-						matches := r.FindStringSubmatch(v)
-
-						if matches == nil || len(matches) < 2 {
-							missingFlag := fmt.Errorf(
-								"no flag found: RFC 8659 point 4.1.1",
-							)
-							errs = append(errs, missingFlag)
-							return
-						}
-
-						flag, err := strconv.Atoi(matches[1])
-						if err != nil {
-							invalidFlag := fmt.Errorf(
-								"flag is not int: RFC 8659 point 4.1.1",
-							)
-							errs = append(errs, invalidFlag)
-							return
-						}
-
-						if 0 > flag || flag > 255 {
-							invalidFlag := fmt.Errorf(
-								"flag must be between 0 and 255: RFC 8659 4.1.1",
-							)
-							errs = append(errs, invalidFlag)
-							return
-						}
-
-						return
-					},
-				),
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ExactlyOneOf:     []string{"content", "tag"},
+				Description:      "Content for the record.",
+				ValidateFunc:     validateCAAContent,
+				DiffSuppressFunc: suppressEquivalentCAAContent,
+			},
+			"flags": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				Description:   "Flags of the record, alternative to content.",
+				ValidateFunc:  validation.IntBetween(0, 255),
+			},
+			"tag": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"value"},
+				Description:   "Property tag of the record, alternative to content.",
+				ValidateFunc:  validation.StringInSlice(dnsrecord.CAATags, true),
+			},
+			"value": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"tag"},
+				Description: "Property value of the record, without quotes, " +
+					"alternative to content.",
 			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("CAA"),
+			resourceRecordCAACustomizeDiff,
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordCAAImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordCAACreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_caa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_caa")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "CAA",
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_caa")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(saved.ID)
 
-	return resourceRecordCAARead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_caa")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordCAARead(ctx, d, m)...)
 
 }
 
@@ -130,16 +143,16 @@ func resourceRecordCAARead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_caa")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setCAAAttributes(d, record.Content)
 
 			return diags
 		}
@@ -152,40 +165,55 @@ func resourceRecordCAARead(
 func resourceRecordCAAUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordCAARead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_caa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_caa")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "CAA",
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_caa")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordCAARead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_caa")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordCAARead(ctx, d, m)...)
 }
 
 func resourceRecordCAADelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_caa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "CAA", "njalla_record_caa")
 }
 
 func resourceRecordCAAImport(
@@ -198,7 +226,7 @@ func resourceRecordCAAImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -209,9 +237,9 @@ func resourceRecordCAAImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setCAAAttributes(d, record.Content)
 
 			return []*schema.ResourceData{d}, nil
 		}
@@ -219,3 +247,121 @@ func resourceRecordCAAImport(
 
 	return nil, fmt.Errorf("Couldn't find record %s for domain %s", id, domain)
 }
+
+// resourceRecordCAACustomizeDiff keeps `content` and the `flags`, `tag` and
+// `value` attributes in sync at plan time, deriving one form from the other
+// one used in the configuration.
+func resourceRecordCAACustomizeDiff(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	raw := d.GetRawConfig()
+
+	if !raw.GetAttr("content").IsNull() {
+		if !d.NewValueKnown("content") {
+			return nil
+		}
+
+		parsed, err := dnsrecord.ParseCAA(d.Get("content").(string))
+		if err != nil {
+			// Already reported by `validateCAAContent`.
+			return nil
+		}
+
+		return setCAADiff(d, parsed)
+	}
+
+	for _, key := range []string{"flags", "tag", "value"} {
+		if !raw.GetAttr(key).IsWhollyKnown() {
+			return d.SetNewComputed("content")
+		}
+	}
+
+	structured := dnsrecord.CAAContent{
+		Flags: rawConfigInt(raw, "flags"),
+		Tag:   strings.ToLower(rawConfigString(raw, "tag")),
+		Value: rawConfigString(raw, "value"),
+	}
+	if err := structured.Validate(); err != nil {
+		return err
+	}
+
+	if err := setCAADiff(d, structured); err != nil {
+		return err
+	}
+
+	old, _ := d.GetChange("content")
+	if canonicalCAAContent(old.(string)) == structured.String() {
+		return d.SetNew("content", old)
+	}
+
+	return d.SetNew("content", structured.String())
+}
+
+// setCAADiff sets the planned `flags`, `tag` and `value` from parsed content,
+// only touching those that actually change.
+func setCAADiff(d *schema.ResourceDiff, parsed dnsrecord.CAAContent) error {
+	values := map[string]interface{}{
+		"flags": parsed.Flags,
+		"tag":   parsed.Tag,
+		"value": parsed.Value,
+	}
+
+	for key, value := range values {
+		if d.Get(key) == value {
+			continue
+		}
+		if err := d.SetNew(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setCAAAttributes sets `content` and, if it can be parsed, `flags`, `tag`
+// and `value` from the content of a record returned by the API.
+func setCAAAttributes(d *schema.ResourceData, content string) {
+	d.Set("content", content)
+
+	parsed, err := dnsrecord.ParseCAA(content)
+	if err != nil {
+		return
+	}
+
+	d.Set("flags", parsed.Flags)
+	d.Set("tag", parsed.Tag)
+	d.Set("value", parsed.Value)
+}
+
+// suppressEquivalentCAAContent ignores differences between contents with the
+// same canonical serialization, such as a quoted and an unquoted value or a
+// different case in the tag.
+func suppressEquivalentCAAContent(k, old, new string, d *schema.ResourceData) bool {
+	canonical := canonicalCAAContent(new)
+
+	return canonical != "" && canonical == canonicalCAAContent(old)
+}
+
+// canonicalCAAContent returns the canonical serialization of a CAA content,
+// or an empty string if it's not valid.
+func canonicalCAAContent(content string) string {
+	parsed, err := dnsrecord.ParseCAA(content)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.String()
+}
+
+// validateCAAContent will be the `ValidateFunc` used to check a given
+// content for a CAA DNS record matches the specification. Check RFC 8659
+// point 4: https://tools.ietf.org/html/rfc8659
+func validateCAAContent(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	if _, err := dnsrecord.ParseCAA(val.(string)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return
+}
@@ -1,17 +1,26 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
-	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_caa", &resource.Sweeper{
+		Name: "njalla_record_caa",
+		F:    testSweepRecords("CAA"),
+	})
+}
+
 func TestAccRecordCAA_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -100,7 +109,7 @@ func TestAccRecordCAA_Update(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"njalla_record_caa.test_update",
 						"content",
-						`0 iodef "letsencrypt.org"`,
+						`0 iodef "mailto:security@example.com"`,
 					),
 				),
 			},
@@ -219,6 +228,172 @@ func TestAccRecordCAA_InvalidContentFlagTooBig(t *testing.T) {
 	})
 }
 
+func TestAccRecordCAA_Structured(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCAADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCAAStructured("letsencrypt.org"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCAAExists(
+						"njalla_record_caa.test_structured",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_caa.test_structured",
+						"content",
+						`128 issue "letsencrypt.org; validationmethods=dns-01"`,
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordCAAStructured("sectigo.com"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"njalla_record_caa.test_structured",
+						"content",
+						`128 issue "sectigo.com; validationmethods=dns-01"`,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_caa.test_structured", "flags", "128",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordCAA_UnquotedContent(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCAADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCAAUnquotedContent(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCAAExists(
+						"njalla_record_caa.test_unquoted_content",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_caa.test_unquoted_content",
+						"tag",
+						"issuewild",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_caa.test_unquoted_content",
+						"value",
+						";",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordCAA_InvalidIodef(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"iodef value must be a mailto:, http: or https: URL",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCAADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordCAAInvalidIodef(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestSuppressEquivalentCAAContent(t *testing.T) {
+	if !suppressEquivalentCAAContent(
+		"content", `0 issue "letsencrypt.org"`, `0 Issue letsencrypt.org`, nil,
+	) {
+		t.Fatal("Expected quoting and tag case differences to be suppressed")
+	}
+
+	if suppressEquivalentCAAContent(
+		"content", `0 issue "letsencrypt.org"`, `128 issue "letsencrypt.org"`,
+		nil,
+	) {
+		t.Fatal("Expected a flags difference to not be suppressed")
+	}
+}
+
+func TestSetCAAAttributes(t *testing.T) {
+	valid := map[string]dnsrecord.CAAContent{
+		`0 issue "letsencrypt.org"`: {Tag: "issue", Value: "letsencrypt.org"},
+		`0 ISSUE letsencrypt.org`:   {Tag: "issue", Value: "letsencrypt.org"},
+		`0 issue ";"`:               {Tag: "issue", Value: ";"},
+		`0 issue ""`:                {Tag: "issue"},
+		`128 issuewild "ca.example.net; accounturi=https://ca.example.net/acct/1; validationmethods=dns-01,http-01"`: {
+			Flags: 128,
+			Tag:   "issuewild",
+			Value: "ca.example.net; accounturi=https://ca.example.net/acct/1; " +
+				"validationmethods=dns-01,http-01",
+		},
+		`0 issuemail "ca.example.net"`: {Tag: "issuemail", Value: "ca.example.net"},
+		`0 iodef "mailto:security@example.com"`: {
+			Tag: "iodef", Value: "mailto:security@example.com",
+		},
+		`0 iodef "https://iodef.example.com/"`: {
+			Tag: "iodef", Value: "https://iodef.example.com/",
+		},
+		`0 issue "ca.example.net; key=\"quoted\""`: {
+			Tag: "issue", Value: `ca.example.net; key="quoted"`,
+		},
+	}
+
+	for content, expected := range valid {
+		if _, errs := validateCAAContent(content, "content"); len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %q: %v", content, errs)
+		}
+
+		d := schema.TestResourceDataRaw(
+			t, resourceRecordCAA().Schema, map[string]interface{}{},
+		)
+		setCAAAttributes(d, content)
+		parsed := dnsrecord.CAAContent{
+			Flags: d.Get("flags").(int),
+			Tag:   d.Get("tag").(string),
+			Value: d.Get("value").(string),
+		}
+		if parsed != expected {
+			t.Fatalf("Parsed %q as %+v, expected %+v", content, parsed, expected)
+		}
+		if canonical := canonicalCAAContent(parsed.String()); canonical != parsed.String() {
+			t.Fatalf("%q didn't round trip: %q", content, canonical)
+		}
+	}
+
+	invalid := []string{
+		`0 issue`,
+		`256 issue "letsencrypt.org"`,
+		`0 unknown "letsencrypt.org"`,
+		`0 issue "-letsencrypt.org"`,
+		`0 issue "letsencrypt.org; accounturi"`,
+		`0 issue "letsencrypt.org; accounturi=not a uri"`,
+		`0 issue "letsencrypt.org; validationmethods=dns-01,,http-01"`,
+		`0 issue "letsencrypt.org`,
+		`0 issue "letsencrypt.org" extra`,
+		`0 issue lets encrypt`,
+		`0 iodef "letsencrypt.org"`,
+		`0 iodef "mailto:"`,
+		`0 iodef "ftp://example.com"`,
+	}
+
+	for _, content := range invalid {
+		if _, errs := validateCAAContent(content, "content"); len(errs) == 0 {
+			t.Fatalf("Unexpected success for %q", content)
+		}
+	}
+}
+
 func testAccCheckRecordCAADestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -228,7 +403,9 @@ func testAccCheckRecordCAADestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -261,7 +438,9 @@ func testAccCheckRecordCAAExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -312,7 +491,7 @@ resource njalla_record_caa test_update {
   domain = %q
   name = "testacc2-caa-update-name2"
   ttl = 3600
-  content = "0 iodef \"letsencrypt.org\""
+  content = "0 iodef \"mailto:security@example.com\""
 }
 `, domain)
 }
@@ -375,3 +554,41 @@ resource njalla_record_caa test_flag_too_big {
 }
 `, domain)
 }
+
+func testAccCheckRecordCAAStructured(issuer string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_caa test_structured {
+  domain = %q
+  name = "testacc9-caa-structured-name"
+  ttl = 10800
+  flags = 128
+  tag = "issue"
+  value = "%s; validationmethods=dns-01"
+}
+`, domain, issuer)
+}
+
+func testAccCheckRecordCAAUnquotedContent() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_caa test_unquoted_content {
+  domain = %q
+  name = "testacc10-caa-unquotedcontent-name"
+  ttl = 10800
+  content = "0 issuewild ;"
+}
+`, domain)
+}
+
+func testAccCheckRecordCAAInvalidIodef() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_caa test_invalid_iodef {
+  domain = %q
+  name = "testacc11-caa-invalidiodef-name"
+  ttl = 10800
+  content = "0 iodef \"letsencrypt.org\""
+}
+`, domain)
+}
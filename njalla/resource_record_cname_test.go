@@ -1,17 +1,25 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_cname", &resource.Sweeper{
+		Name: "njalla_record_cname",
+		F:    testSweepRecords("CNAME"),
+	})
+}
+
 func TestAccRecordCNAME_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -185,6 +193,215 @@ func TestAccRecordCNAME_InvalidTTL(t *testing.T) {
 	})
 }
 
+func TestAccRecordCNAME_InvalidContentIP(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected content to be a hostname, got the IP address 1.2.3.4",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCNAMEDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordCNAMEInvalidContentIP(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordCNAME_TrailingDot(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCNAMEDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCNAMETrailingDot("."),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCNAMEExists(
+						"njalla_record_cname.test_trailing_dot",
+					),
+				),
+			},
+			{
+				Config:   testAccCheckRecordCNAMETrailingDot(""),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccRecordCNAME_InternationalizedContent(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCNAMEDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCNAMEInternationalizedContent(
+					"testacc8-cname-bücher-content.com",
+				),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCNAMEExists(
+						"njalla_record_cname.test_idn_content",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_cname.test_idn_content",
+						"content",
+						"testacc8-cname-bücher-content.com",
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordCNAMEInternationalizedContent(
+					"xn--testacc8-cname-bcher-content-h7c.com.",
+				),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccRecordCNAME_InternationalizedDomain(t *testing.T) {
+	if testAccMockServer == nil {
+		t.Skip("Needs an internationalized domain, only run against the fake API")
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordCNAMEIDNDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCNAMEInternationalizedDomain(
+					"testacc-bücher.com",
+					"testacc9-cname-idn-name.testacc-bücher.com.",
+				),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCNAMEIDNStored(
+						"testacc9-cname-idn-name",
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordCNAMEInternationalizedDomain(
+					testAccMockIDNDomain, "testacc9-cname-idn-name",
+				),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccRecordCNAME_Conflict(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"CNAME record testacc10-cname-conflict-name in domain .+ conflicts " +
+			"with the existing A record",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		CheckDestroy: func(s *terraform.State) error {
+			if err := testAccCheckRecordCNAMEDestroy(s); err != nil {
+				return err
+			}
+			return testAccCheckRecordADestroy(s)
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCNAMEConflict(false),
+			},
+			{
+				Config:      testAccCheckRecordCNAMEConflict(true),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordCNAME_ReplaceA(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"CNAME record testacc11-cname-replacea-name in domain .+ conflicts " +
+			"with the existing A record",
+	)
+
+	// Not a parallel test, so no other test is running while the interval
+	// is shortened. Its configuration has a provider block, so it uses
+	// factories, as `Providers` always adds an empty one.
+	interval := cnameConflictInterval
+	cnameConflictInterval = 100 * time.Millisecond
+	defer func() { cnameConflictInterval = interval }()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		ProviderFactories: map[string]func() (*schema.Provider, error){
+			"njalla": func() (*schema.Provider, error) {
+				return testAccProvider, nil
+			},
+		},
+		CheckDestroy: func(s *terraform.State) error {
+			if err := testAccCheckRecordCNAMEDestroy(s); err != nil {
+				return err
+			}
+			return testAccCheckRecordADestroy(s)
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordCNAMEReplaceA(false, ""),
+			},
+			{
+				// The A record destroyed in the same run still conflicts
+				// at plan time by default.
+				Config:      testAccCheckRecordCNAMEReplaceA(true, "error"),
+				ExpectError: expectedErr,
+			},
+			{
+				Config: testAccCheckRecordCNAMEReplaceA(true, "wait"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordCNAMEExists(
+						"njalla_record_cname.test_replace_a",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_cname.test_replace_a",
+						"name",
+						"testacc11-cname-replacea-name",
+					),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckRecordCNAMEIDNStored checks the fake API stored a record with
+// the given name in the internationalized domain, which means the domain was
+// sent in its ASCII form.
+func testAccCheckRecordCNAMEIDNStored(name string) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		for _, record := range testAccMockServer.Records(testAccMockIDNDomain) {
+			if record.Name == name {
+				return nil
+			}
+		}
+
+		return fmt.Errorf(
+			"Record %s doesn't exist for domain %s", name, testAccMockIDNDomain,
+		)
+	}
+}
+
+func testAccCheckRecordCNAMEIDNDestroy(s *terraform.State) error {
+	for _, record := range testAccMockServer.Records(testAccMockIDNDomain) {
+		if record.Type == "CNAME" {
+			return fmt.Errorf("Record %s still exists", record.ID)
+		}
+	}
+
+	return nil
+}
+
 func testAccCheckRecordCNAMEDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -194,7 +411,9 @@ func testAccCheckRecordCNAMEDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -227,7 +446,9 @@ func testAccCheckRecordCNAMEExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -317,3 +538,107 @@ resource njalla_record_cname test_invalid_ttl {
 }
 `, domain)
 }
+
+func testAccCheckRecordCNAMEInvalidContentIP() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_cname test_invalid_content_ip {
+  domain = %q
+  name = "testacc6-cname-invalidcontentip-name"
+  ttl = 10800
+  content = "1.2.3.4"
+}
+`, domain)
+}
+
+func testAccCheckRecordCNAMETrailingDot(dot string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_cname test_trailing_dot {
+  domain = %q
+  name = "testacc7-cname-trailingdot-name"
+  ttl = 10800
+  content = "testacc7-cname-trailingdot-content.com%s"
+}
+`, domain, dot)
+}
+
+func testAccCheckRecordCNAMEInternationalizedContent(content string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_cname test_idn_content {
+  domain = %q
+  name = "testacc8-cname-idncontent-name"
+  ttl = 10800
+  content = %q
+}
+`, domain, content)
+}
+
+func testAccCheckRecordCNAMEInternationalizedDomain(
+	domain string, name string,
+) string {
+	return fmt.Sprintf(`
+resource njalla_record_cname test_idn_domain {
+  domain = %q
+  name = %q
+  ttl = 10800
+  content = "testacc9-cname-idn-content.com"
+}
+`, domain, name)
+}
+
+func testAccCheckRecordCNAMEConflict(withCNAME bool) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	config := fmt.Sprintf(`
+resource njalla_record_a test_conflict {
+  domain = %q
+  name = "testacc10-cname-conflict-name"
+  ttl = 10800
+  content = "1.1.1.10"
+}
+`, domain)
+
+	if withCNAME {
+		config += fmt.Sprintf(`
+resource njalla_record_cname test_conflict {
+  domain = %q
+  name = "testacc10-cname-conflict-name"
+  ttl = 10800
+  content = "testacc10-cname-conflict-content.com"
+}
+`, domain)
+	}
+
+	return config
+}
+
+// testAccCheckRecordCNAMEReplaceA returns a config with either an A record,
+// or a CNAME replacing it under the provider's `cname_conflicts`.
+func testAccCheckRecordCNAMEReplaceA(cname bool, conflicts string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+
+	if !cname {
+		return fmt.Sprintf(`
+resource njalla_record_a test_replace_a {
+  domain = %q
+  name = "testacc11-cname-replacea-name"
+  ttl = 10800
+  content = "1.1.1.11"
+}
+`, domain)
+	}
+
+	return fmt.Sprintf(`
+provider "njalla" {
+  cname_conflicts = %q
+}
+
+resource njalla_record_cname test_replace_a {
+  domain = %q
+  name = "testacc11-cname-replacea-name"
+  ttl = 10800
+  content = "testacc11-cname-replacea-content.com"
+}
+`, conflicts, domain)
+}
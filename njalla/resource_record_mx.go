@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
@@ -12,7 +13,7 @@ import (
 )
 
 func resourceRecordMX() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordMXCreate,
 		ReadContext:   resourceRecordMXRead,
 		UpdateContext: resourceRecordMXUpdate,
@@ -20,10 +21,12 @@ func resourceRecordMX() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -31,14 +34,13 @@ func resourceRecordMX() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"priority": {
 				Type:         schema.TypeInt,
 				Required:     true,
@@ -46,42 +48,66 @@ func resourceRecordMX() *schema.Resource {
 				ValidateFunc: validation.IntInSlice(gonjalla.ValidPriority),
 			},
 			"content": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Content for the record.",
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Content for the record.",
+				ValidateFunc:     validateHostname(false),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("MX"),
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordMXImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordMXCreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_mx"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 	priority := d.Get("priority").(int)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_mx")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:     "MX",
-		Name:     d.Get("name").(string),
-		Content:  d.Get("content").(string),
-		TTL:      d.Get("ttl").(int),
+		Name:     recordName(d),
+		Content:  hostnameContent(d),
+		TTL:      ttl,
 		Priority: &priority,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_mx")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(fmt.Sprint(saved.ID))
 
-	return resourceRecordMXRead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_mx")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordMXRead(ctx, d, m)...)
 
 }
 
@@ -94,17 +120,17 @@ func resourceRecordMXRead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_mx")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("priority", *record.Priority)
-			d.Set("content", record.Content)
+			setHostnameContent(d, record.Content)
 
 			return diags
 		}
@@ -117,42 +143,57 @@ func resourceRecordMXRead(
 func resourceRecordMXUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordMXRead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_mx"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 	priority := d.Get("priority").(int)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_mx")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:       d.Id(),
-		Name:     d.Get("name").(string),
+		Name:     recordName(d),
 		Type:     "MX",
-		Content:  d.Get("content").(string),
-		TTL:      d.Get("ttl").(int),
+		Content:  hostnameContent(d),
+		TTL:      ttl,
 		Priority: &priority,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_mx")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_mx")...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordMXRead(ctx, d, m)
+	return append(diags, resourceRecordMXRead(ctx, d, m)...)
 }
 
 func resourceRecordMXDelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_mx"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "MX", "njalla_record_mx")
 }
 
 func resourceRecordMXImport(
@@ -165,7 +206,7 @@ func resourceRecordMXImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -176,10 +217,10 @@ func resourceRecordMXImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
 			d.Set("priority", *record.Priority)
-			d.Set("content", record.Content)
+			setHostnameContent(d, record.Content)
 
 			return []*schema.ResourceData{d}, nil
 		}
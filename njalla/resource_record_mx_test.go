@@ -1,6 +1,7 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,15 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_mx", &resource.Sweeper{
+		Name: "njalla_record_mx",
+		F:    testSweepRecords("MX"),
+	})
+}
+
 func TestAccRecordMX_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -215,6 +221,24 @@ func TestAccRecordMX_InvalidPriority(t *testing.T) {
 	})
 }
 
+func TestAccRecordMX_InvalidContentURL(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected content to be a hostname, got what looks like a URL",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordMXDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordMXInvalidContentURL(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
 func testAccCheckRecordMXDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -224,7 +248,9 @@ func testAccCheckRecordMXDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -257,7 +283,9 @@ func testAccCheckRecordMXExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -366,3 +394,16 @@ resource njalla_record_mx test_invalid_priority {
 }
 `, domain)
 }
+
+func testAccCheckRecordMXInvalidContentURL() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_mx test_invalid_content_url {
+  domain = %q
+  name = "testacc7-mx-invalidcontenturl-name"
+  ttl = 10800
+  priority = 10
+  content = "https://mail.example.com"
+}
+`, domain)
+}
@@ -3,18 +3,18 @@ package njalla
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
 func resourceRecordNAPTR() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordNAPTRCreate,
 		ReadContext:   resourceRecordNAPTRRead,
 		UpdateContext: resourceRecordNAPTRUpdate,
@@ -22,10 +22,12 @@ func resourceRecordNAPTR() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -33,50 +35,124 @@ func resourceRecordNAPTR() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
 				Type:         schema.TypeString,
-				Required:     true,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"content", "order"},
 				Description:  "Content for the record.",
 				ValidateFunc: validateNAPTRContent,
 			},
+			"order": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"order", "preference"},
+				Description:   "Order of the record, alternative to content.",
+				ValidateFunc:  validation.IntBetween(0, 65535),
+			},
+			"preference": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"order", "preference"},
+				Description:   "Preference of the record, alternative to content.",
+				ValidateFunc:  validation.IntBetween(0, 65535),
+			},
+			"flags": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				Description:   "Flags of the record, alternative to content.",
+				ValidateFunc:  stringValidator(dnsrecord.ValidateNAPTRFlags),
+			},
+			"service": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				Description:   "Service of the record, alternative to content.",
+				ValidateFunc:  stringValidator(dnsrecord.ValidateNAPTRService),
+			},
+			"regexp": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				Description:   "Regexp of the record, alternative to content.",
+				ValidateFunc:  stringValidator(dnsrecord.ValidateNAPTRRegexp),
+			},
+			"replacement": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				Description:   "Replacement of the record, alternative to content.",
+				ValidateFunc:  stringValidator(dnsrecord.ValidateNAPTRReplacement),
+			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("NAPTR"),
+			resourceRecordNAPTRCustomizeDiff,
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordNAPTRImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordNAPTRCreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_naptr"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_naptr")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "NAPTR",
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_naptr")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(saved.ID)
 
-	return resourceRecordNAPTRRead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_naptr")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordNAPTRRead(ctx, d, m)...)
 
 }
 
@@ -89,16 +165,16 @@ func resourceRecordNAPTRRead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_naptr")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setNAPTRAttributes(d, record.Content)
 
 			return diags
 		}
@@ -111,40 +187,55 @@ func resourceRecordNAPTRRead(
 func resourceRecordNAPTRUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordNAPTRRead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_naptr"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_naptr")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "NAPTR",
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_naptr")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_naptr")...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordNAPTRRead(ctx, d, m)
+	return append(diags, resourceRecordNAPTRRead(ctx, d, m)...)
 }
 
 func resourceRecordNAPTRDelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_naptr"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "NAPTR", "njalla_record_naptr")
 }
 
 func resourceRecordNAPTRImport(
@@ -157,7 +248,7 @@ func resourceRecordNAPTRImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -168,9 +259,9 @@ func resourceRecordNAPTRImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setNAPTRAttributes(d, record.Content)
 
 			return []*schema.ResourceData{d}, nil
 		}
@@ -179,45 +270,117 @@ func resourceRecordNAPTRImport(
 	return nil, fmt.Errorf("Couldn't find record %s for domain %s", id, domain)
 }
 
-// validateNAPTRContent will be the `ValidateFunc` used to check a given
-// content for a NAPTR DNS record matches the specification. If you're up for
-// some heavy reading, check RFC 2915 section 2:
-// https://tools.ietf.org/html/rfc2915
-func validateNAPTRContent(
-	val interface{}, key string,
-) (warns []string, errs []error) {
-	v := val.(string)
-	values := strings.Split(v, " ")
+// resourceRecordNAPTRCustomizeDiff keeps `content` and the structured
+// attributes in sync at plan time. Whichever form is used in the
+// configuration is the source of truth, and the other one is derived from
+// it, so the plan shows the final values of both.
+func resourceRecordNAPTRCustomizeDiff(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	if !d.GetRawConfig().GetAttr("content").IsNull() {
+		if !d.NewValueKnown("content") {
+			return nil
+		}
+
+		parsed, err := dnsrecord.ParseNAPTR(d.Get("content").(string))
+		if err != nil {
+			// Already reported by `validateNAPTRContent`.
+			return nil
+		}
 
-	rfc := "Check RFC 2915 section 2"
+		return setNAPTRDiff(d, parsed)
+	}
 
-	if len(values) < 6 {
-		msg := fmt.Errorf(
-			"expected 6+ arguments, got: %d. %s",
-			len(values), rfc,
-		)
-		errs = append(errs, msg)
-		return
+	for _, key := range naptrStructuredKeys {
+		if !d.GetRawConfig().GetAttr(key).IsWhollyKnown() {
+			return d.SetNewComputed("content")
+		}
 	}
 
-	_, err := strconv.Atoi(values[0])
-	if err != nil {
-		msg := fmt.Errorf(
-			"expected Order field to be int, got: %s. %s",
-			values[0], rfc,
-		)
-		errs = append(errs, msg)
-		return
+	// Read from the configuration rather than `d.Get`, as attributes
+	// removed from the configuration would otherwise keep their computed
+	// values from the state.
+	raw := d.GetRawConfig()
+	structured := dnsrecord.NAPTRContent{
+		Order:       rawConfigInt(raw, "order"),
+		Preference:  rawConfigInt(raw, "preference"),
+		Flags:       rawConfigString(raw, "flags"),
+		Service:     rawConfigString(raw, "service"),
+		Regexp:      rawConfigString(raw, "regexp"),
+		Replacement: rawConfigString(raw, "replacement"),
+	}
+	if structured.Replacement == "" {
+		structured.Replacement = "."
+	}
+
+	if err := structured.Validate(); err != nil {
+		return err
+	}
+
+	if err := setNAPTRDiff(d, structured); err != nil {
+		return err
+	}
+
+	return d.SetNew("content", structured.String())
+}
+
+// naptrStructuredKeys are the attributes making up a NAPTR record's content.
+var naptrStructuredKeys = []string{
+	"order", "preference", "flags", "service", "regexp", "replacement",
+}
+
+// setNAPTRDiff sets the planned structured attributes from parsed content,
+// only touching those that actually change.
+func setNAPTRDiff(d *schema.ResourceDiff, parsed dnsrecord.NAPTRContent) error {
+	values := map[string]interface{}{
+		"order":       parsed.Order,
+		"preference":  parsed.Preference,
+		"flags":       parsed.Flags,
+		"service":     parsed.Service,
+		"regexp":      parsed.Regexp,
+		"replacement": parsed.Replacement,
 	}
 
-	_, err = strconv.Atoi(values[1])
+	for _, key := range naptrStructuredKeys {
+		if d.Get(key) == values[key] {
+			continue
+		}
+		if err := d.SetNew(key, values[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setNAPTRAttributes sets `content` and, if it can be parsed, the
+// structured attributes from the content of a record returned by the API.
+func setNAPTRAttributes(d *schema.ResourceData, content string) {
+	d.Set("content", content)
+
+	parsed, err := dnsrecord.ParseNAPTR(content)
 	if err != nil {
-		msg := fmt.Errorf(
-			"expected Preference field to be int, got: %s. %s",
-			values[1], rfc,
-		)
-		errs = append(errs, msg)
 		return
 	}
+
+	d.Set("order", parsed.Order)
+	d.Set("preference", parsed.Preference)
+	d.Set("flags", parsed.Flags)
+	d.Set("service", parsed.Service)
+	d.Set("regexp", parsed.Regexp)
+	d.Set("replacement", parsed.Replacement)
+}
+
+// validateNAPTRContent will be the `ValidateFunc` used to check a given
+// content for a NAPTR DNS record matches the specification. If you're up for
+// some heavy reading, check RFC 3403 section 4.1:
+// https://tools.ietf.org/html/rfc3403
+func validateNAPTRContent(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	if _, err := dnsrecord.ParseNAPTR(val.(string)); err != nil {
+		errs = append(errs, err)
+	}
+
 	return
 }
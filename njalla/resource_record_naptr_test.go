@@ -1,17 +1,26 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 
-	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_naptr", &resource.Sweeper{
+		Name: "njalla_record_naptr",
+		F:    testSweepRecords("NAPTR"),
+	})
+}
+
 func TestAccRecordNAPTR_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -187,7 +196,7 @@ func TestAccRecordNAPTR_InvalidTTL(t *testing.T) {
 
 func TestAccRecordNAPTR_InvalidContent(t *testing.T) {
 	expectedErr := regexp.MustCompile(
-		`expected 6\+ arguments, .* Check RFC 2915`,
+		`expected 6 arguments, .* Check RFC 3403`,
 	)
 
 	resource.ParallelTest(t, resource.TestCase{
@@ -239,6 +248,163 @@ func TestAccRecordNAPTR_StringPreference(t *testing.T) {
 	})
 }
 
+func TestAccRecordNAPTR_Structured(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordNAPTRDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordNAPTRStructured(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordNAPTRExists(
+						"njalla_record_naptr.test_structured",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_structured",
+						"content",
+						`100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_structured",
+						"replacement",
+						".",
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordNAPTRCreate(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_create", "order", "100",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_create", "preference", "10",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_create", "flags", "",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_create",
+						"regexp",
+						`/urn:cid:.+@([^\.]+\.)(.*)$/\2/i`,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_naptr.test_create", "replacement", ".",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordNAPTR_InvalidFlags(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		`expected Flags field to contain only one of S, A or U, got: SA`,
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordNAPTRDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordNAPTRInvalidFlags(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordNAPTR_InvalidBackreference(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		`backreference \\3 to refer to one of the 2 groups`,
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordNAPTRDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordNAPTRInvalidBackreference(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestSetNAPTRAttributes(t *testing.T) {
+	valid := map[string]dnsrecord.NAPTRContent{
+		`100 10 "" "" "/urn:cid:.+@([^\.]+\.)(.*)$/\2/i" .`: {
+			Order: 100, Preference: 10,
+			Regexp: `/urn:cid:.+@([^\.]+\.)(.*)$/\2/i`, Replacement: ".",
+		},
+		`100 50 "s" "http+I2L+I2C+I2R" "" _http._tcp.gatech.edu.`: {
+			Order: 100, Preference: 50, Flags: "s",
+			Service: "http+I2L+I2C+I2R", Replacement: "_http._tcp.gatech.edu.",
+		},
+		`10 0 "u" "E2U+pstn:tel" "!^(.*)$!tel:\1!" .`: {
+			Order: 10, Flags: "u", Service: "E2U+pstn:tel",
+			Regexp: `!^(.*)$!tel:\1!`, Replacement: ".",
+		},
+		`0 0 "P" "" "#a b#c\#d#" .`: {
+			Flags: "P", Regexp: `#a b#c\#d#`, Replacement: ".",
+		},
+	}
+
+	for content, expected := range valid {
+		if _, errs := validateNAPTRContent(content, "content"); len(errs) > 0 {
+			t.Fatalf("Unexpected errors for %q: %v", content, errs)
+		}
+
+		d := schema.TestResourceDataRaw(
+			t, resourceRecordNAPTR().Schema, map[string]interface{}{},
+		)
+		setNAPTRAttributes(d, content)
+		parsed := dnsrecord.NAPTRContent{
+			Order:       d.Get("order").(int),
+			Preference:  d.Get("preference").(int),
+			Flags:       d.Get("flags").(string),
+			Service:     d.Get("service").(string),
+			Regexp:      d.Get("regexp").(string),
+			Replacement: d.Get("replacement").(string),
+		}
+		if parsed != expected {
+			t.Fatalf("Parsed %q as %+v, expected %+v", content, parsed, expected)
+		}
+		if _, errs := validateNAPTRContent(parsed.String(), "content"); len(errs) > 0 {
+			t.Fatalf("%q didn't round trip: %v", content, errs)
+		}
+	}
+
+	invalid := []string{
+		`100 10 "" "" "" . extra`,
+		`100 10 S "" "" .`,
+		`100 10 "" http "" .`,
+		`100 10 "" "" /a/b/ .`,
+		`100 10 "" "" "" "."`,
+		`100 10 "X" "" "" .`,
+		`100 10 "SS" "" "" .`,
+		`100 10 "U" "E2U+sip" "" .`,
+		`100 10 "" "1http" "" .`,
+		`100 10 "" "" "1a1b1" .`,
+		`100 10 "" "" "/a/b" .`,
+		`100 10 "" "" "/a/b/g" .`,
+		`100 10 "" "" "/(a/b/" .`,
+		`100 10 "" "" "/a//" example.com.`,
+		`100 10 "" "" "" -example.com.`,
+		`100 10 "" "" "unterminated .`,
+		`100 10 """" "" "" .`,
+	}
+
+	for _, content := range invalid {
+		if _, errs := validateNAPTRContent(content, "content"); len(errs) == 0 {
+			t.Fatalf("Unexpected success for %q", content)
+		}
+	}
+}
+
 func testAccCheckRecordNAPTRDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -248,7 +414,9 @@ func testAccCheckRecordNAPTRDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -281,7 +449,9 @@ func testAccCheckRecordNAPTRExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -407,3 +577,47 @@ resource njalla_record_naptr test_string_preference {
 }
 `, domain)
 }
+
+func testAccCheckRecordNAPTRStructured() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_naptr test_structured {
+  domain = %q
+  name = "testacc9-naptr-structured-name"
+  ttl = 10800
+  order = 100
+  preference = 10
+  flags = "U"
+  service = "E2U+sip"
+  regexp = "!^.*$!sip:info@example.com!"
+}
+`, domain)
+}
+
+func testAccCheckRecordNAPTRInvalidFlags() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_naptr test_invalid_flags {
+  domain = %q
+  name = "testacc10-naptr-invalidflags-name"
+  ttl = 10800
+  content = "100 50 \"SA\" \"http+I2L+I2C+I2R\" \"\" _http._tcp.gatech.edu."
+}
+`, domain)
+}
+
+func testAccCheckRecordNAPTRInvalidBackreference() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_naptr test_invalid_backreference {
+  domain = %q
+  name = "testacc11-naptr-invalidbackreference-name"
+  ttl = 10800
+  order = 100
+  preference = 10
+  flags = "U"
+  service = "E2U+sip"
+  regexp = "!^(a)(b)$!sip:\\3@example.com!"
+}
+`, domain)
+}
@@ -1,6 +1,7 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,15 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_ns", &resource.Sweeper{
+		Name: "njalla_record_ns",
+		F:    testSweepRecords("NS"),
+	})
+}
+
 func TestAccRecordNS_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -150,6 +156,24 @@ func TestAccRecordNS_InvalidTTL(t *testing.T) {
 	})
 }
 
+func TestAccRecordNS_InvalidContent(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected content to be a valid hostname, got: _ns.example.com",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordNSDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordNSInvalidContent(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
 func testAccCheckRecordNSDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -159,7 +183,9 @@ func testAccCheckRecordNSDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -192,7 +218,9 @@ func testAccCheckRecordNSExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -271,3 +299,15 @@ resource njalla_record_ns test_invalid_ttl {
 }
 `, domain)
 }
+
+func testAccCheckRecordNSInvalidContent() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_ns test_invalid_content {
+  domain = %q
+  name = "testacc5-ns-invalidcontent-name"
+  ttl = 10800
+  content = "_ns.example.com"
+}
+`, domain)
+}
@@ -1,6 +1,7 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,15 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_ptr", &resource.Sweeper{
+		Name: "njalla_record_ptr",
+		F:    testSweepRecords("PTR"),
+	})
+}
+
 func TestAccRecordPTR_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -185,6 +191,24 @@ func TestAccRecordPTR_InvalidTTL(t *testing.T) {
 	})
 }
 
+func TestAccRecordPTR_InvalidContent(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected content to be a valid hostname, got: -ptr.example.com",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordPTRDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordPTRInvalidContent(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
 func testAccCheckRecordPTRDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -194,7 +218,9 @@ func testAccCheckRecordPTRDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -227,7 +253,9 @@ func testAccCheckRecordPTRExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -317,3 +345,15 @@ resource njalla_record_ptr test_invalid_ttl {
 }
 `, domain)
 }
+
+func testAccCheckRecordPTRInvalidContent() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_ptr test_invalid_content {
+  domain = %q
+  name = "testacc6-ptr-invalidcontent-name"
+  ttl = 10800
+  content = "-ptr.example.com"
+}
+`, domain)
+}
@@ -0,0 +1,665 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// A record set manages every record of a type at a name, one per value.
+// Changes are reconciled with the records in the zone, so adding a value
+// only adds its record, and removing one only removes its record, whatever
+// the order of the values in the configuration.
+
+// recordSetTypes returns the types a record set can have. Their values are
+// checked by `dnsrecord.Validate`, like the content of the resource of each
+// type. CNAMEs aren't allowed, as there can only be one at a name.
+func recordSetTypes() []string {
+	return []string{"A", "AAAA", "CAA", "MX", "NS", "PTR", "TXT"}
+}
+
+// recordSetValue is one of the `values` of a record set.
+type recordSetValue struct {
+	Value    string
+	Priority int
+}
+
+func resourceRecordSet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceRecordSetCreate,
+		ReadContext:   resourceRecordSetRead,
+		UpdateContext: resourceRecordSetUpdate,
+		DeleteContext: resourceRecordSetDelete,
+
+		Schema: map[string]*schema.Schema{
+			"domain": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain the records will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				DefaultFunc: func() (interface{}, error) {
+					return "@", nil
+				},
+				Description:      "Name for the records.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
+			},
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				Description:  "Type of the records.",
+				ValidateFunc: validation.StringInSlice(recordSetTypes(), false),
+			},
+			"ttl":            ttlSchema(),
+			"adopt_existing": adoptExistingSchema(),
+			"values": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				MinItems:    1,
+				Description: "Values of the records, one record each.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"value": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Content of the record.",
+						},
+						"priority": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Description:  "Priority of the record, only for MX records.",
+							ValidateFunc: validation.IntInSlice(gonjalla.ValidPriority),
+						},
+					},
+				},
+			},
+			"wait_for_propagation": waitForPropagationSchema(),
+			"record_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Njalla IDs of the records of the set.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+		},
+
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			resourceRecordSetCustomizeDiff,
+		),
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceRecordSetImport,
+		},
+
+		Timeouts: recordTimeouts(),
+	}
+}
+
+func resourceRecordSetCreate(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_set"); diags != nil {
+		return diags
+	}
+
+	domain := d.Get("domain").(string)
+	recordType := d.Get("type").(string)
+	name := recordName(d)
+
+	ttl, diags := config.recordTTL(d, "njalla_record_set")
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, config.waitForCNAMEConflicts(
+		ctx, domain, recordType, name, "", "create", "njalla_record_set",
+	)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return append(diags, diagFromAPIError(err, "create", "njalla_record_set")...)
+	}
+
+	existing := recordSetRecords(records, domain, recordType, name)
+	if len(existing) > 0 && !config.adoptExisting(d) {
+		return append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Records already exist for njalla_record_set",
+			Detail: fmt.Sprintf(
+				"Domain %s already has %d %s records at %s. Import them "+
+					"with the ID %s, or set adopt_existing = true to take "+
+					"them over.",
+				domain, len(existing), recordType, name,
+				recordSetID(domain, name, recordType),
+			),
+		})
+	}
+
+	// With an `owner_id`, foreign records are refused by
+	// `reconcileRecordSet` instead.
+	if len(existing) > 0 && config.OwnerID == "" {
+		if marker := findOwnerMarker(records, domain, recordType, name); marker != nil {
+			owner, _ := parseOwnerMarker(marker.Content)
+			return append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Records owned by another owner for njalla_record_set",
+				Detail: fmt.Sprintf(
+					"Refusing to adopt the %s records at %s in domain %s, as "+
+						"their ownership marker %s names the owner %q.",
+					recordType, name, domain,
+					ownerMarkerName(recordType, name), owner,
+				),
+			})
+		}
+	}
+
+	setDiags := config.reconcileRecordSet(
+		ctx, records, domain, recordType, name, ttl, recordSetValues(d),
+		"create",
+	)
+	diags = append(diags, setDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	d.SetId(recordSetID(domain, name, recordType))
+
+	diags = append(diags, waitForPropagationRecords(
+		ctx, d, domain, recordSetValueRecords(d, recordType, name, ttl),
+		"njalla_record_set",
+	)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordSetRead(ctx, d, m)...)
+}
+
+func resourceRecordSetRead(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+
+	domain := d.Get("domain").(string)
+	recordType := d.Get("type").(string)
+
+	var diags diag.Diagnostics
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return diagFromAPIError(err, "read", "njalla_record_set")
+	}
+
+	existing := recordSetRecords(records, domain, recordType, recordName(d))
+	if len(existing) == 0 {
+		d.SetId("")
+		return diags
+	}
+
+	setRecordSetAttributes(d, config, existing)
+
+	return diags
+}
+
+func resourceRecordSetUpdate(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordSetRead(ctx, d, m)
+	}
+
+	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_set"); diags != nil {
+		return diags
+	}
+
+	domain := d.Get("domain").(string)
+	recordType := d.Get("type").(string)
+
+	ttl, diags := config.recordTTL(d, "njalla_record_set")
+	if diags.HasError() {
+		return diags
+	}
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return diagFromAPIError(err, "update", "njalla_record_set")
+	}
+
+	setDiags := config.reconcileRecordSet(
+		ctx, records, domain, recordType, recordName(d), ttl,
+		recordSetValues(d), "update",
+	)
+	diags = append(diags, setDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagationRecords(
+		ctx, d, domain, recordSetValueRecords(d, recordType, recordName(d), ttl),
+		"njalla_record_set",
+	)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordSetRead(ctx, d, m)...)
+}
+
+func resourceRecordSetDelete(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) diag.Diagnostics {
+	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_set"); diags != nil {
+		return diags
+	}
+
+	domain := d.Get("domain").(string)
+	recordType := d.Get("type").(string)
+	name := recordName(d)
+
+	if diags := config.checkDeletable(domain, recordType, name, "njalla_record_set"); diags != nil {
+		return diags
+	}
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return diagFromAPIError(err, "delete", "njalla_record_set")
+	}
+
+	if config.OwnerID != "" {
+		diags := config.checkOwnership(
+			records, domain, recordType, name, "delete", "njalla_record_set",
+		)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	for _, record := range recordSetRecords(records, domain, recordType, name) {
+		if err := config.removeRecord(ctx, domain, record.ID); err != nil {
+			return diagFromAPIError(err, "delete", "njalla_record_set")
+		}
+	}
+
+	if config.OwnerID != "" {
+		if err := config.releaseOwnership(ctx, domain, recordType, name, ""); err != nil {
+			return diagFromAPIError(err, "delete", "njalla_record_set")
+		}
+	}
+
+	var diags diag.Diagnostics
+	return diags
+}
+
+func resourceRecordSetImport(
+	ctx context.Context, d *schema.ResourceData, m interface{},
+) ([]*schema.ResourceData, error) {
+	domain, name, recordType, err := parseRecordSetID(d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	config := m.(*Config)
+
+	records, err := config.listRecords(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Reading records for domain %s failed: %s", domain, err.Error(),
+		)
+	}
+
+	existing := recordSetRecords(records, domain, recordType, name)
+	if len(existing) == 0 {
+		return nil, fmt.Errorf(
+			"Couldn't find %s records at %s for domain %s",
+			recordType, name, domain,
+		)
+	}
+
+	d.SetId(recordSetID(domain, name, recordType))
+	d.Set("domain", domain)
+	d.Set("name", name)
+	d.Set("type", recordType)
+	setRecordSetAttributes(d, config, existing)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// resourceRecordSetCustomizeDiff checks every value is valid for the type of
+// the records, that only MX records have priorities, and that the records
+// don't conflict with a CNAME.
+func resourceRecordSetCustomizeDiff(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	if !d.NewValueKnown("type") {
+		return nil
+	}
+
+	recordType := d.Get("type").(string)
+	typeValidator := validation.StringInSlice(recordSetTypes(), false)
+	if _, errs := typeValidator(recordType, "type"); len(errs) > 0 {
+		// Already reported by the `type` validation.
+		return nil
+	}
+
+	if d.NewValueKnown("values") {
+		for _, v := range d.Get("values").(*schema.Set).List() {
+			value := v.(map[string]interface{})
+
+			err := dnsrecord.Validate(
+				"values.value", recordType, value["value"].(string),
+			)
+			if err != nil {
+				return err
+			}
+
+			if !dnsrecord.HasPriority(recordType) && value["priority"].(int) != 0 {
+				return fmt.Errorf(
+					"expected values.priority to only be set for MX records, "+
+						"got %d for %s",
+					value["priority"], value["value"],
+				)
+			}
+		}
+	}
+
+	return validateCNAMEConflicts(recordType)(ctx, d, m)
+}
+
+// reconcileRecordSet makes the records of `recordType` at `name` match
+// `values`, out of `records`, the records of the domain. Records whose
+// value is still wanted are kept, with their TTL edited if needed, missing
+// values are added, and records whose value isn't wanted anymore are
+// removed. Nothing is changed if the set is protected and a record would be
+// edited or removed.
+func (c *Config) reconcileRecordSet(
+	ctx context.Context,
+	records []gonjalla.Record,
+	domain string,
+	recordType string,
+	name string,
+	ttl int,
+	values []recordSetValue,
+	operation string,
+) diag.Diagnostics {
+	if c.OwnerID != "" {
+		diags := c.checkOwnership(
+			records, domain, recordType, name, operation, "njalla_record_set",
+		)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	existing := recordSetRecords(records, domain, recordType, name)
+	kept := make([]bool, len(existing))
+
+	var edited, added []gonjalla.Record
+	for _, value := range values {
+		record := value.record(recordType, name, ttl)
+
+		found := false
+		for i, current := range existing {
+			if kept[i] || !dnsrecord.SameRecord(current, record, domain) {
+				continue
+			}
+
+			kept[i], found = true, true
+			if current.TTL != ttl {
+				record.ID = current.ID
+				edited = append(edited, record)
+			}
+			break
+		}
+
+		if !found {
+			added = append(added, record)
+		}
+	}
+
+	var removed []gonjalla.Record
+	for i, current := range existing {
+		if !kept[i] {
+			removed = append(removed, current)
+		}
+	}
+
+	if len(removed) > 0 {
+		if diags := c.checkDeletable(domain, recordType, name, "njalla_record_set"); diags != nil {
+			return diags
+		}
+	}
+
+	// The set's ID is only saved once every change is made, so records
+	// added by a failed call are removed again instead of being left behind
+	// for the next apply to trip over.
+	var addedIDs []string
+	fail := func(err error) diag.Diagnostics {
+		diags := diagFromAPIError(err, operation, "njalla_record_set")
+		return append(diags, c.removeAddedRecords(ctx, domain, addedIDs)...)
+	}
+
+	for _, record := range edited {
+		if err := c.editRecord(ctx, domain, record); err != nil {
+			return fail(err)
+		}
+	}
+
+	for _, record := range added {
+		saved, err := c.addRecord(ctx, domain, record)
+		if err != nil {
+			return fail(err)
+		}
+		addedIDs = append(addedIDs, saved.ID)
+	}
+
+	for _, record := range removed {
+		if err := c.removeRecord(ctx, domain, record.ID); err != nil {
+			return fail(err)
+		}
+	}
+
+	if c.OwnerID != "" {
+		if err := c.claimOwnership(ctx, records, domain, recordType, name); err != nil {
+			return fail(err)
+		}
+	}
+
+	return nil
+}
+
+// removeAddedRecords removes the records added by a failed
+// `reconcileRecordSet`, returning a warning naming those it couldn't remove.
+func (c *Config) removeAddedRecords(
+	ctx context.Context, domain string, ids []string,
+) diag.Diagnostics {
+	var left []string
+	for _, id := range ids {
+		if err := c.removeRecord(ctx, domain, id); err != nil {
+			left = append(left, id)
+		}
+	}
+
+	if len(left) == 0 {
+		return nil
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Warning,
+			Summary:  "Records left behind by njalla_record_set",
+			Detail: fmt.Sprintf(
+				"The records %s were added to domain %s before the change "+
+					"failed, and couldn't be removed again. Set "+
+					"adopt_existing = true to take them over, or remove them.",
+				strings.Join(left, ", "), domain,
+			),
+		},
+	}
+}
+
+// record returns the record of a value, ready to be sent to the API.
+func (v recordSetValue) record(recordType string, name string, ttl int) gonjalla.Record {
+	record := gonjalla.Record{
+		Type:    recordType,
+		Name:    name,
+		Content: dnsrecord.Content(recordType, v.Value),
+		TTL:     ttl,
+	}
+
+	if dnsrecord.HasPriority(recordType) {
+		priority := v.Priority
+		record.Priority = &priority
+	}
+
+	return record
+}
+
+// recordSetValues returns the `values` of a record set.
+func recordSetValues(d *schema.ResourceData) []recordSetValue {
+	var values []recordSetValue
+	for _, v := range d.Get("values").(*schema.Set).List() {
+		value := v.(map[string]interface{})
+		values = append(values, recordSetValue{
+			Value:    value["value"].(string),
+			Priority: value["priority"].(int),
+		})
+	}
+
+	return values
+}
+
+// recordSetValueRecords returns the records of the `values` of a record
+// set, as sent to the API.
+func recordSetValueRecords(
+	d *schema.ResourceData, recordType string, name string, ttl int,
+) []gonjalla.Record {
+	values := recordSetValues(d)
+	records := make([]gonjalla.Record, 0, len(values))
+	for _, value := range values {
+		records = append(records, value.record(recordType, name, ttl))
+	}
+
+	return records
+}
+
+// recordSetRecords returns the records of `recordType` at `name`, in
+// canonical form, out of the records of a domain.
+func recordSetRecords(
+	records []gonjalla.Record, domain string, recordType string, name string,
+) []gonjalla.Record {
+	var set []gonjalla.Record
+	for _, record := range records {
+		if record.Type != recordType {
+			continue
+		}
+
+		if !strings.EqualFold(dnsrecord.NormalizeName(record.Name, domain), name) {
+			continue
+		}
+
+		set = append(set, record)
+	}
+
+	return set
+}
+
+// setRecordSetAttributes sets `ttl`, `values` and `record_ids` from the
+// records of a set returned by the API. Values equivalent to the current ones are kept as
+// written, so they don't show up as changes. If the records have different
+// TTLs, one differing from the current `ttl` is used, so it shows up as a
+// change.
+func setRecordSetAttributes(
+	d *schema.ResourceData, config *Config, records []gonjalla.Record,
+) {
+	recordType := d.Get("type").(string)
+	domain := d.Get("domain").(string)
+	current := recordSetValues(d)
+
+	ttl := records[0].TTL
+	if resolved, _, err := config.resolveTTL(d.Get("ttl").(string)); err == nil {
+		ttl = resolved
+		for _, record := range records {
+			if record.TTL != resolved {
+				ttl = record.TTL
+				break
+			}
+		}
+	}
+	setRecordTTL(d, config, ttl)
+
+	values := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		value := recordSetValue{
+			Value: dnsrecord.Value(recordType, record.Content),
+		}
+		if record.Priority != nil && dnsrecord.HasPriority(recordType) {
+			value.Priority = *record.Priority
+		}
+
+		for _, c := range current {
+			if dnsrecord.SameRecord(record, c.record(recordType, record.Name, record.TTL), domain) {
+				value = c
+				break
+			}
+		}
+
+		values = append(values, map[string]interface{}{
+			"value":    value.Value,
+			"priority": value.Priority,
+		})
+	}
+
+	d.Set("values", values)
+
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	sort.Strings(ids)
+	d.Set("record_ids", ids)
+}
+
+// recordSetID returns the ID of a record set: `domain:name:type`, with the
+// domain in ASCII form and the name in canonical form.
+func recordSetID(domain string, name string, recordType string) string {
+	return fmt.Sprintf("%s:%s:%s", dnsrecord.ToASCII(domain), name, recordType)
+}
+
+// parseRecordSetID parses the ID of a record set, like `example.com:www:A`.
+func parseRecordSetID(id string) (string, string, string, error) {
+	parts := strings.Split(id, ":")
+
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf(
+			"unexpected format of ID (%s), expected domain:name:type", id,
+		)
+	}
+
+	domain := parts[0]
+	return domain, dnsrecord.NormalizeName(parts[1], domain),
+		strings.ToUpper(parts[2]), nil
+}
@@ -0,0 +1,390 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccRecordSet_Reconcile(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	ids := map[string]string{}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordSetA("1.1.1.1", "1.1.1.2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"njalla_record_set.test_a", "id",
+						fmt.Sprintf("%s:testacc1-set-a-name:A", domain),
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_set.test_a", "values.#", "2",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_set.test_a", "record_ids.#", "2",
+					),
+					testAccCheckRecordSetRecords(
+						"testacc1-set-a-name", "A", ids,
+						"1.1.1.1", "1.1.1.2",
+					),
+				),
+			},
+			{
+				// Reordering the values isn't a change.
+				Config:   testAccCheckRecordSetA("1.1.1.2", "1.1.1.1"),
+				PlanOnly: true,
+			},
+			{
+				Config: testAccCheckRecordSetA("1.1.1.2", "1.1.1.3", "1.1.1.1"),
+				Check: testAccCheckRecordSetRecords(
+					"testacc1-set-a-name", "A", ids,
+					"1.1.1.1", "1.1.1.2", "1.1.1.3",
+				),
+			},
+			{
+				Config: testAccCheckRecordSetA("1.1.1.3", "1.1.1.1"),
+				Check: testAccCheckRecordSetRecords(
+					"testacc1-set-a-name", "A", ids,
+					"1.1.1.1", "1.1.1.3",
+				),
+			},
+			{
+				ResourceName:      "njalla_record_set.test_a",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccRecordSet_MX(t *testing.T) {
+	ids := map[string]string{}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordSetMX(10, 20),
+				Check: testAccCheckRecordSetRecords(
+					"testacc2-set-mx-name", "MX", ids,
+					"10 mx1.example.com", "20 mx2.example.com",
+				),
+			},
+			{
+				// Changing a priority only replaces that record.
+				Config: testAccCheckRecordSetMX(10, 30),
+				Check: testAccCheckRecordSetRecords(
+					"testacc2-set-mx-name", "MX", ids,
+					"10 mx1.example.com", "30 mx2.example.com",
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordSet_InvalidValue(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordSetA("1.1.1.1", "testacc3-set-invalid"),
+				ExpectError: regexp.MustCompile(
+					"expected values.value to contain a valid IPv4 address",
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordSet_InvalidPriority(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource njalla_record_set test_priority {
+  domain = %q
+  name = "testacc4-set-priority-name"
+  type = "A"
+
+  values {
+    value = "1.1.1.1"
+    priority = 10
+  }
+}
+`, domain),
+				ExpectError: regexp.MustCompile(
+					"expected values.priority to only be set for MX records",
+				),
+			},
+		},
+	})
+}
+
+func TestParseRecordSetID(t *testing.T) {
+	domain, name, recordType, err := parseRecordSetID("example.com:WWW.example.com.:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain != "example.com" || name != "WWW" || recordType != "A" {
+		t.Fatalf("Unexpected %s, %s, %s", domain, name, recordType)
+	}
+
+	for _, id := range []string{"example.com:www", "example.com::A", "a:b:c:d"} {
+		if _, _, _, err := parseRecordSetID(id); err == nil {
+			t.Errorf("Expected an error parsing %s", id)
+		}
+	}
+}
+
+// testAccCheckRecordSetRecords checks the API has exactly the records of
+// `values` of `recordType` at `name`, with values of MX records prefixed by
+// their priority. Records with a value already in `ids` must have kept the
+// same ID, so values that didn't change weren't replaced. `ids` is updated
+// with the current records.
+func testAccCheckRecordSetRecords(
+	name string, recordType string, ids map[string]string, values ...string,
+) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		config := testAccProvider.Meta().(*Config)
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		current := map[string]string{}
+		var found []string
+		for _, record := range records {
+			if record.Type != recordType || record.Name != name {
+				continue
+			}
+
+			value := record.Content
+			if record.Priority != nil {
+				value = fmt.Sprintf("%d %s", *record.Priority, value)
+			}
+			found = append(found, value)
+			current[value] = record.ID
+
+			if id, ok := ids[value]; ok && id != record.ID {
+				return fmt.Errorf(
+					"Record %s with value %s was replaced by %s",
+					id, value, record.ID,
+				)
+			}
+		}
+
+		sort.Strings(found)
+		sort.Strings(values)
+		if strings.Join(found, ",") != strings.Join(values, ",") {
+			return fmt.Errorf(
+				"Expected %s records %v at %s, got %v",
+				recordType, values, name, found,
+			)
+		}
+
+		for value, id := range current {
+			ids[value] = id
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckRecordSetDestroy(s *terraform.State) error {
+	config := testAccProvider.Meta().(*Config)
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "njalla_record_set" {
+			continue
+		}
+
+		domain, name, recordType, err := parseRecordSetID(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		if set := recordSetRecords(records, domain, recordType, name); len(set) > 0 {
+			return fmt.Errorf(
+				"%d %s records still exist at %s in domain %s",
+				len(set), recordType, name, domain,
+			)
+		}
+	}
+
+	return nil
+}
+
+func testAccCheckRecordSetA(values ...string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+
+	var blocks strings.Builder
+	for _, value := range values {
+		fmt.Fprintf(&blocks, "\n  values {\n    value = %q\n  }\n", value)
+	}
+
+	return fmt.Sprintf(`
+resource njalla_record_set test_a {
+  domain = %q
+  name = "testacc1-set-a-name"
+  type = "A"
+  ttl = 3600
+%s}
+`, domain, blocks.String())
+}
+
+func testAccCheckRecordSetMX(priority1 int, priority2 int) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_set test_mx {
+  domain = %q
+  name = "testacc2-set-mx-name"
+  type = "MX"
+
+  values {
+    value = "mx1.example.com"
+    priority = %d
+  }
+
+  values {
+    value = "mx2.example.com"
+    priority = %d
+  }
+}
+`, domain, priority1, priority2)
+}
+
+func TestRecordSetCreatePartialFailure(t *testing.T) {
+	config, server := newTestCRUDConfig(t)
+	server.FailAfter("add-record", 1, 500, "Internal error")
+
+	r := resourceRecordSet()
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"domain": "testing.com",
+		"name":   "www",
+		"type":   "A",
+		"ttl":    "3600",
+		"values": []interface{}{
+			map[string]interface{}{"value": "1.1.1.1"},
+			map[string]interface{}{"value": "2.2.2.2"},
+		},
+	})
+
+	diags := r.CreateContext(context.Background(), d, config)
+	if !diags.HasError() {
+		t.Fatal("Expected Create to fail")
+	}
+	if d.Id() != "" {
+		t.Fatalf("Expected no ID saved, got %s", d.Id())
+	}
+	if records := server.Records("testing.com"); len(records) != 0 {
+		t.Fatalf("Expected the added record removed again, got %v", records)
+	}
+
+	// With nothing left behind, the next apply doesn't need adopt_existing.
+	if diags := r.CreateContext(context.Background(), d, config); diags.HasError() {
+		t.Fatalf("Retrying Create failed: %v", diags)
+	}
+	if records := server.Records("testing.com"); len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %v", records)
+	}
+}
+
+func TestRecordSetProtectedShrink(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed bool
+		// remaining is the number of records expected after the update.
+		remaining int
+	}{
+		{"protected", false, 2},
+		{"allowed", true, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config, server := newTestCRUDConfig(t)
+			config.ProtectedRecords, _ = parseRecordPatterns(nil, true)
+			config.AllowProtectedDeletion = c.allowed
+			ctx := context.Background()
+
+			raw := func(values ...string) map[string]interface{} {
+				var set []interface{}
+				for _, v := range values {
+					set = append(set, map[string]interface{}{"value": v})
+				}
+
+				return map[string]interface{}{
+					"domain": "testing.com",
+					"name":   "@",
+					"type":   "NS",
+					"ttl":    "3600",
+					"values": set,
+				}
+			}
+
+			r := resourceRecordSet()
+			d := schema.TestResourceDataRaw(
+				t, r.Schema, raw("ns1.testing.com.", "ns2.testing.com."),
+			)
+			if diags := r.CreateContext(ctx, d, config); diags.HasError() {
+				t.Fatalf("Create failed: %v", diags)
+			}
+			if ids := d.Get("record_ids").([]interface{}); len(ids) != 2 {
+				t.Fatalf("Expected 2 record_ids, got %v", ids)
+			}
+
+			updated := schema.TestResourceDataRaw(
+				t, r.Schema, raw("ns1.testing.com."),
+			)
+			updated.SetId(d.Id())
+			diags := r.UpdateContext(ctx, updated, config)
+			if diags.HasError() == c.allowed {
+				t.Fatalf("Unexpected Update diagnostics: %v", diags)
+			}
+
+			if records := server.Records("testing.com"); len(records) != c.remaining {
+				t.Fatalf(
+					"Expected %d records left, got %v", c.remaining, records,
+				)
+			}
+		})
+	}
+}
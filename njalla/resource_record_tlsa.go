@@ -2,19 +2,24 @@ package njalla
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
 func resourceRecordTLSA() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordTLSACreate,
 		ReadContext:   resourceRecordTLSARead,
 		UpdateContext: resourceRecordTLSAUpdate,
@@ -22,10 +27,12 @@ func resourceRecordTLSA() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -33,50 +40,115 @@ func resourceRecordTLSA() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
 				Type:         schema.TypeString,
-				Required:     true,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"content", "certificate_pem"},
 				Description:  "Content for the record.",
 				ValidateFunc: validateTLSAContent,
 			},
+			"usage": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"certificate_pem"},
+				Description:   "Certificate usage, alternative to content.",
+				ValidateFunc:  validation.IntBetween(0, 255),
+			},
+			"selector": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"certificate_pem"},
+				Description: "Selector, 0 for the full certificate or 1 for " +
+					"its public key, alternative to content.",
+				ValidateFunc: validation.IntBetween(0, 1),
+			},
+			"matching_type": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"certificate_pem"},
+				Description: "Matching type, 0 for no hash, 1 for SHA-256 or " +
+					"2 for SHA-512, alternative to content.",
+				ValidateFunc: validation.IntBetween(0, 2),
+			},
+			"certificate_pem": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"content"},
+				RequiredWith:  []string{"usage", "selector", "matching_type"},
+				Description: "PEM encoded certificate the association data " +
+					"is derived from, alternative to content.",
+				ValidateFunc: stringValidator(func(v string) error {
+					_, err := parseCertificatePEM(v)
+					return err
+				}),
+			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("TLSA"),
+			resourceRecordTLSACustomizeDiff,
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordTLSAImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordTLSACreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_tlsa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_tlsa")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "TLSA",
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_tlsa")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(saved.ID)
 
-	return resourceRecordTLSARead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_tlsa")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordTLSARead(ctx, d, m)...)
 
 }
 
@@ -89,16 +161,16 @@ func resourceRecordTLSARead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_tlsa")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setTLSAAttributes(d, record.Content)
 
 			return diags
 		}
@@ -111,40 +183,55 @@ func resourceRecordTLSARead(
 func resourceRecordTLSAUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordTLSARead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_tlsa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_tlsa")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "TLSA",
 		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_tlsa")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_tlsa")...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordTLSARead(ctx, d, m)
+	return append(diags, resourceRecordTLSARead(ctx, d, m)...)
 }
 
 func resourceRecordTLSADelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_tlsa"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "TLSA", "njalla_record_tlsa")
 }
 
 func resourceRecordTLSAImport(
@@ -157,7 +244,7 @@ func resourceRecordTLSAImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -168,9 +255,9 @@ func resourceRecordTLSAImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setTLSAAttributes(d, record.Content)
 
 			return []*schema.ResourceData{d}, nil
 		}
@@ -179,85 +266,168 @@ func resourceRecordTLSAImport(
 	return nil, fmt.Errorf("Couldn't find record %s for domain %s", id, domain)
 }
 
-// validateTLSAContent will be the `ValidateFunc` used to check a given
-// content for a TLSA DNS record matches the specification. If you're up for
-// some heavy reading, check RFC 6698 points 2 and 7:
-// https://tools.ietf.org/html/rfc6698
-func validateTLSAContent(
-	val interface{}, key string,
-) (warns []string, errs []error) {
-	v := val.(string)
-	values := strings.Split(v, " ")
+// resourceRecordTLSACustomizeDiff derives `content` from
+// `certificate_pem` and the usage, selector and matching type when those are
+// used, or the separate fields from `content` otherwise, so the plan shows
+// the final values of both.
+func resourceRecordTLSACustomizeDiff(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	raw := d.GetRawConfig()
+
+	if !raw.GetAttr("content").IsNull() {
+		if !d.NewValueKnown("content") {
+			return nil
+		}
 
-	rfc := "Check RFC 6698 sections 2 and 7"
+		parsed, err := dnsrecord.ParseTLSA(d.Get("content").(string))
+		if err != nil {
+			// Already reported by `validateTLSAContent`.
+			return nil
+		}
 
-	if len(values) != 4 {
-		msg := fmt.Errorf(
-			"expected 4 arguments, got: %d. %s",
-			len(values), rfc,
-		)
-		errs = append(errs, msg)
-		return
+		return setTLSADiff(d, parsed)
 	}
 
-	certificateUsage, err := strconv.Atoi(values[0])
+	for _, key := range []string{
+		"usage", "selector", "matching_type", "certificate_pem",
+	} {
+		if !raw.GetAttr(key).IsWhollyKnown() {
+			return d.SetNewComputed("content")
+		}
+	}
+
+	data, err := tlsaAssociationData(
+		rawConfigString(raw, "certificate_pem"),
+		rawConfigInt(raw, "selector"),
+		rawConfigInt(raw, "matching_type"),
+	)
 	if err != nil {
-		msg := fmt.Errorf(
-			"expected Certificate Usage field to be int, got: %s. %s",
-			values[0], rfc,
-		)
-		errs = append(errs, msg)
-		return
+		return err
 	}
 
-	if certificateUsage < 0 || certificateUsage > 255 {
-		msg := fmt.Errorf(
-			"expected Certificate Usage field to be between 0 and 255 "+
-				"(inclusive), got: %d. %s",
-			certificateUsage, rfc,
-		)
-		errs = append(errs, msg)
-		return
+	derived := dnsrecord.TLSAContent{
+		Usage:        rawConfigInt(raw, "usage"),
+		Selector:     rawConfigInt(raw, "selector"),
+		MatchingType: rawConfigInt(raw, "matching_type"),
+		Data:         data,
 	}
 
-	selector, err := strconv.Atoi(values[1])
+	if d.Get("content") == derived.String() {
+		return nil
+	}
+
+	return d.SetNew("content", derived.String())
+}
+
+// setTLSADiff sets the planned usage, selector and matching type from parsed
+// content, only touching those that actually change.
+func setTLSADiff(d *schema.ResourceDiff, parsed dnsrecord.TLSAContent) error {
+	values := map[string]int{
+		"usage":         parsed.Usage,
+		"selector":      parsed.Selector,
+		"matching_type": parsed.MatchingType,
+	}
+
+	for key, value := range values {
+		if d.Get(key) == value {
+			continue
+		}
+		if err := d.SetNew(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setTLSAAttributes sets `content` and, if it can be parsed, the usage,
+// selector and matching type from the content of a record returned by the
+// API. `certificate_pem` can't be recovered from the content, so it's left
+// as is.
+func setTLSAAttributes(d *schema.ResourceData, content string) {
+	d.Set("content", content)
+
+	parsed, err := dnsrecord.ParseTLSA(content)
 	if err != nil {
-		msg := fmt.Errorf(
-			"expected Selector field to be int, got: %s. %s",
-			values[1], rfc,
-		)
-		errs = append(errs, msg)
 		return
 	}
 
-	if selector < 0 || selector > 255 {
-		msg := fmt.Errorf(
-			"expected Selector field to be between 0 and 255 (inclusive), "+
-				"got: %d. %s",
-			selector, rfc,
+	d.Set("usage", parsed.Usage)
+	d.Set("selector", parsed.Selector)
+	d.Set("matching_type", parsed.MatchingType)
+}
+
+// parseCertificatePEM decodes the first PEM block of `v`, which must be a
+// certificate.
+func parseCertificatePEM(v string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(v))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf(
+			"expected a PEM encoded CERTIFICATE block in certificate_pem",
 		)
-		errs = append(errs, msg)
-		return
 	}
 
-	matchingType, err := strconv.Atoi(values[2])
+	certificate, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
-		msg := fmt.Errorf(
-			"expected Matching Type field to be int, got: %s. %s",
-			values[2], rfc,
-		)
-		errs = append(errs, msg)
-		return
+		return nil, fmt.Errorf("failed to parse certificate_pem: %s", err)
 	}
 
-	if matchingType < 0 || matchingType > 255 {
-		msg := fmt.Errorf(
-			"expected Matching Type field to be between 0 and 255 "+
-				"(inclusive), got: %d. %s",
-			matchingType, rfc,
+	return certificate, nil
+}
+
+// tlsaAssociationData derives the hexadecimal Certificate Association Data of
+// a TLSA record from a PEM encoded certificate. The selector picks the full
+// certificate (0) or its SubjectPublicKeyInfo (1), and the matching type
+// whether that is used as is (0), or hashed with SHA-256 (1) or SHA-512 (2).
+func tlsaAssociationData(
+	certificatePEM string, selector int, matchingType int,
+) (string, error) {
+	certificate, err := parseCertificatePEM(certificatePEM)
+	if err != nil {
+		return "", err
+	}
+
+	var selected []byte
+	switch selector {
+	case 0:
+		selected = certificate.Raw
+	case 1:
+		selected = certificate.RawSubjectPublicKeyInfo
+	default:
+		return "", fmt.Errorf(
+			"can't derive association data for Selector %d, expected 0 or 1",
+			selector,
 		)
-		errs = append(errs, msg)
-		return
+	}
+
+	switch matchingType {
+	case 0:
+		return hex.EncodeToString(selected), nil
+	case 1:
+		digest := sha256.Sum256(selected)
+		return hex.EncodeToString(digest[:]), nil
+	case 2:
+		digest := sha512.Sum512(selected)
+		return hex.EncodeToString(digest[:]), nil
+	}
+
+	return "", fmt.Errorf(
+		"can't derive association data for Matching Type %d, expected 0, 1 "+
+			"or 2",
+		matchingType,
+	)
+}
+
+// validateTLSAContent will be the `ValidateFunc` used to check a given
+// content for a TLSA DNS record matches the specification. If you're up for
+// some heavy reading, check RFC 6698 points 2 and 7:
+// https://tools.ietf.org/html/rfc6698
+func validateTLSAContent(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	if _, err := dnsrecord.ParseTLSA(val.(string)); err != nil {
+		errs = append(errs, err)
 	}
 
 	return
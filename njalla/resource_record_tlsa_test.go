@@ -1,6 +1,7 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,15 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-
-	"github.com/Sighery/gonjalla"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_tlsa", &resource.Sweeper{
+		Name: "njalla_record_tlsa",
+		F:    testSweepRecords("TLSA"),
+	})
+}
+
 func TestAccRecordTLSA_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -40,7 +46,7 @@ func TestAccRecordTLSA_Create(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"njalla_record_tlsa.test_create",
 						"content",
-						"3 0 0 30820307308201efa003020102020",
+						"3 0 0 30820307308201efa00302010202",
 					),
 				),
 			},
@@ -76,7 +82,7 @@ func TestAccRecordTLSA_Update(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"njalla_record_tlsa.test_update",
 						"content",
-						"0 0 1 d2abde240d7cd3ee6b4b28c54df034b9",
+						"0 0 1 bdf6d7b8435e0120320d92391826805bb9ab35ee23987682f47b4abaf2931d69",
 					),
 				),
 			},
@@ -100,7 +106,7 @@ func TestAccRecordTLSA_Update(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"njalla_record_tlsa.test_update",
 						"content",
-						"0 0 1 7983a1d16e8a410e4561cb106618e971",
+						"0 0 1 5a163ad9f2209ed17f6be327a8cf2ce14ffb02cbbcde1c356a43e58729db1de9",
 					),
 				),
 			},
@@ -161,7 +167,8 @@ func TestAccRecordTLSA_EmptyName(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"njalla_record_tlsa.test_empty_name",
 						"content",
-						"1 1 2 92003ba34942dc74152e2f2c408d29ec",
+						"1 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f1"+
+							"8bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db",
 					),
 				),
 			},
@@ -311,6 +318,111 @@ func TestAccRecordTLSA_InvalidIntMatchingType(t *testing.T) {
 	})
 }
 
+func TestAccRecordTLSA_CertificatePEM(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTLSADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordTLSACertificatePEM(3, 1, 1),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordTLSAExists(
+						"njalla_record_tlsa.test_certificate_pem",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_tlsa.test_certificate_pem",
+						"content",
+						"3 1 1 "+testAccTLSASPKISHA256,
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordTLSACertificatePEM(2, 0, 1),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"njalla_record_tlsa.test_certificate_pem",
+						"content",
+						"2 0 1 "+testAccTLSACertSHA256,
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_tlsa.test_certificate_pem", "usage", "2",
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordTLSA_InvalidDataLength(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected Certificate Association Data field to be 64 hexadecimal " +
+			"characters for Matching Type 1, got: 32",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTLSADestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordTLSAInvalidDataLength(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+// Digests of testdata/tlsa/cert.pem, as computed by openssl.
+const (
+	testAccTLSACertSHA256 = "2fc9532da6f01e2abd8023269b5735b1065609057f3ec143c3b8c3492ac84460"
+	testAccTLSASPKISHA256 = "b49311bf58cb363b3cecd1172fc30914c736a2fee86535c450c943425ef98abc"
+	testAccTLSASPKISHA512 = "" +
+		"e834be6c302eb5822baaa7cd5923788b2067e5763a41b8877ad29f1d663dab29" +
+		"2f725a1ab0d5d9b02d612b0d7c894caf93de83b289fd609dc75bb708bb2a274d"
+)
+
+func TestTLSAAssociationData(t *testing.T) {
+	certificatePEM, err := os.ReadFile("testdata/tlsa/cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		selector     int
+		matchingType int
+		expected     string
+	}{
+		{0, 1, testAccTLSACertSHA256},
+		{1, 2, testAccTLSASPKISHA512},
+		{
+			1, 0,
+			"3059301306072a8648ce3d020106082a8648ce3d030107034200" +
+				"0418d4abd4a3815619704eff8786eac88d3ad28a022628011ae0e40743714f9b" +
+				"c4a4ffbe5efb13c6e809f1baf4742c6829ed3e02935f66f68e25be7d2311096d0f",
+		},
+	}
+
+	for _, c := range cases {
+		data, err := tlsaAssociationData(
+			string(certificatePEM), c.selector, c.matchingType,
+		)
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+		if data != c.expected {
+			t.Fatalf(
+				"Selector %d Matching Type %d gave %s, expected %s",
+				c.selector, c.matchingType, data, c.expected,
+			)
+		}
+	}
+
+	if _, err := tlsaAssociationData("not a pem", 0, 1); err == nil {
+		t.Fatal("Unexpected success with an invalid certificate")
+	}
+}
+
 func testAccCheckRecordTLSADestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -320,7 +432,9 @@ func testAccCheckRecordTLSADestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -353,7 +467,9 @@ func testAccCheckRecordTLSAExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -380,7 +496,7 @@ resource njalla_record_tlsa test_create {
   domain = %q
   name = "testacc1-tlsa-create-name"
   ttl = 10800
-  content = "3 0 0 30820307308201efa003020102020"
+  content = "3 0 0 30820307308201efa00302010202"
 }
 `, domain)
 }
@@ -392,7 +508,7 @@ resource njalla_record_tlsa test_update {
   domain = %q
   name = "testacc2-tlsa-update-name1"
   ttl = 10800
-  content = "0 0 1 d2abde240d7cd3ee6b4b28c54df034b9"
+  content = "0 0 1 bdf6d7b8435e0120320d92391826805bb9ab35ee23987682f47b4abaf2931d69"
 }
 `, domain)
 }
@@ -404,7 +520,7 @@ resource njalla_record_tlsa test_update {
   domain = %q
   name = "testacc2-tlsa-update-name2"
   ttl = 3600
-  content = "0 0 1 7983a1d16e8a410e4561cb106618e971"
+  content = "0 0 1 5a163ad9f2209ed17f6be327a8cf2ce14ffb02cbbcde1c356a43e58729db1de9"
 }
 `, domain)
 }
@@ -416,7 +532,7 @@ resource njalla_record_tlsa test_import {
   domain = %q
   name = "testacc3-tlsa-import-name"
   ttl = 10800
-  content = "1 1 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "1 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -427,7 +543,7 @@ func testAccCheckRecordTLSAEmptyName() string {
 resource njalla_record_tlsa test_empty_name {
   domain = %q
   ttl = 10800
-  content = "1 1 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "1 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -439,7 +555,7 @@ resource njalla_record_tlsa test_invalid_ttl {
   domain = %q
   name = "testacc5-tlsa-invalidttl-name"
   ttl = 999
-  content = "1 1 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "1 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -463,7 +579,7 @@ resource njalla_record_tlsa test_string_certificate_usage {
   domain = %q
   name = "testacc7-tlsa-stringcertificateusage-name"
   ttl = 10800
-  content = "test 1 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "test 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -475,7 +591,7 @@ resource njalla_record_tlsa test_invalid_int_certificate_usage {
   domain = %q
   name = "testacc8-tlsa-invalidintcertificateusage-name"
   ttl = 10800
-  content = "999 1 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "999 1 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -487,7 +603,7 @@ resource njalla_record_tlsa test_string_selector {
   domain = %q
   name = "testacc9-tlsa-stringselector-name"
   ttl = 10800
-  content = "0 test 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "0 test 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -499,7 +615,7 @@ resource njalla_record_tlsa test_invalid_int_selector {
   domain = %q
   name = "testacc10-tlsa-invalidintselector-name"
   ttl = 10800
-  content = "0 999 2 92003ba34942dc74152e2f2c408d29ec"
+  content = "0 999 2 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -511,7 +627,7 @@ resource njalla_record_tlsa test_string_matching_type {
   domain = %q
   name = "testacc11-tlsa-stringmatchingtype-name"
   ttl = 10800
-  content = "0 1 test 92003ba34942dc74152e2f2c408d29ec"
+  content = "0 1 test a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
 }
 `, domain)
 }
@@ -523,7 +639,38 @@ resource njalla_record_tlsa test_invalid_int_matching_type {
   domain = %q
   name = "testacc12-tlsa-invalidintmatchingtype-name"
   ttl = 10800
-  content = "0 1 999 92003ba34942dc74152e2f2c408d29ec"
+  content = "0 1 999 a2a13d81f61226396c3f30af4e06fa5fcdae05d0084f8534f45031c11095e9f18bb273036a0f370554caf900a872851d9e25ef4cde09aa200b617c8ed826e9db"
+}
+`, domain)
+}
+
+func testAccCheckRecordTLSACertificatePEM(
+	usage int, selector int, matchingType int,
+) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	certificatePEM, _ := os.ReadFile("testdata/tlsa/cert.pem")
+	return fmt.Sprintf(`
+resource njalla_record_tlsa test_certificate_pem {
+  domain = %q
+  name = "testacc13-tlsa-certificatepem-name"
+  ttl = 10800
+  usage = %d
+  selector = %d
+  matching_type = %d
+  certificate_pem = <<EOT
+%sEOT
+}
+`, domain, usage, selector, matchingType, certificatePEM)
+}
+
+func testAccCheckRecordTLSAInvalidDataLength() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_tlsa test_invalid_data_length {
+  domain = %q
+  name = "testacc14-tlsa-invaliddatalength-name"
+  ttl = 10800
+  content = "3 1 1 92003ba34942dc74152e2f2c408d29ec"
 }
 `, domain)
 }
@@ -5,14 +5,15 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
 	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
 func resourceRecordTXT() *schema.Resource {
-	return &schema.Resource{
+	return withTTLStateUpgrader(&schema.Resource{
 		CreateContext: resourceRecordTXTCreate,
 		ReadContext:   resourceRecordTXTRead,
 		UpdateContext: resourceRecordTXTUpdate,
@@ -20,10 +21,12 @@ func resourceRecordTXT() *schema.Resource {
 
 		Schema: map[string]*schema.Schema{
 			"domain": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Specifies the domain this record will be applied to.",
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				Description:      "Specifies the domain this record will be applied to.",
+				ValidateFunc:     stringValidator(validateDomain),
+				DiffSuppressFunc: suppressEquivalentHostname,
 			},
 			"name": {
 				Type:     schema.TypeString,
@@ -31,49 +34,72 @@ func resourceRecordTXT() *schema.Resource {
 				DefaultFunc: func() (interface{}, error) {
 					return "@", nil
 				},
-				Description: "Name for the record.",
-			},
-			"ttl": {
-				Type:         schema.TypeInt,
-				Required:     true,
-				Description:  "TTL for the record.",
-				ValidateFunc: validation.IntInSlice(gonjalla.ValidTTL),
+				Description:      "Name for the record.",
+				ValidateFunc:     validateRecordName,
+				DiffSuppressFunc: suppressEquivalentRecordName,
 			},
+			"ttl":                  ttlSchema(),
+			"adopt_existing":       adoptExistingSchema(),
+			"wait_for_propagation": waitForPropagationSchema(),
 			"content": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Content for the record.",
+				Type:             schema.TypeString,
+				Required:         true,
+				Description:      "Content for the record.",
+				ValidateFunc:     validateTXTContent,
+				DiffSuppressFunc: suppressEquivalentTXTContent,
 			},
 		},
 
+		CustomizeDiff: customdiff.All(
+			validateRecordNameLength,
+			customizeRecordTTL,
+			validateCNAMEConflicts("TXT"),
+		),
+
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceRecordTXTImport,
 		},
-	}
+
+		Timeouts: recordTimeouts(),
+	})
 }
 
 func resourceRecordTXTCreate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("create", "njalla_record_txt"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_txt")
+	if diags.HasError() {
+		return diags
+	}
+
 	record := gonjalla.Record{
 		Type:    "TXT",
-		Name:    d.Get("name").(string),
-		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		Name:    recordName(d),
+		Content: dnsrecord.FormatTXT(d.Get("content").(string)),
+		TTL:     ttl,
 	}
 
-	saved, err := gonjalla.AddRecord(config.Token, domain, record)
-	if err != nil {
-		return diag.FromErr(err)
+	saved, createDiags := config.createRecord(ctx, d, domain, record, "njalla_record_txt")
+	diags = append(diags, createDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
 	d.SetId(fmt.Sprint(saved.ID))
 
-	return resourceRecordTXTRead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, record, "njalla_record_txt")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordTXTRead(ctx, d, m)...)
 
 }
 
@@ -86,16 +112,16 @@ func resourceRecordTXTRead(
 
 	var diags diag.Diagnostics
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, "read", "njalla_record_txt")
 	}
 
 	for _, record := range records {
 		if d.Id() == record.ID {
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setTXTContent(d, record.Content)
 
 			return diags
 		}
@@ -108,40 +134,55 @@ func resourceRecordTXTRead(
 func resourceRecordTXTUpdate(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
+	if onlySettingsChanged(d) {
+		return resourceRecordTXTRead(ctx, d, m)
+	}
+
 	config := m.(*Config)
+	if diags := config.checkWritable("update", "njalla_record_txt"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
+	ttl, diags := config.recordTTL(d, "njalla_record_txt")
+	if diags.HasError() {
+		return diags
+	}
+
 	updateRecord := gonjalla.Record{
 		ID:      d.Id(),
-		Name:    d.Get("name").(string),
+		Name:    recordName(d),
 		Type:    "TXT",
-		Content: d.Get("content").(string),
-		TTL:     d.Get("ttl").(int),
+		Content: dnsrecord.FormatTXT(d.Get("content").(string)),
+		TTL:     ttl,
 	}
 
-	err := gonjalla.EditRecord(config.Token, domain, updateRecord)
-	if err != nil {
-		return diag.FromErr(err)
+	updateDiags := config.updateRecord(ctx, d, domain, updateRecord, "njalla_record_txt")
+	diags = append(diags, updateDiags...)
+	if diags.HasError() {
+		return diags
 	}
 
-	return resourceRecordTXTRead(ctx, d, m)
+	diags = append(diags, waitForPropagation(ctx, d, domain, updateRecord, "njalla_record_txt")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	return append(diags, resourceRecordTXTRead(ctx, d, m)...)
 }
 
 func resourceRecordTXTDelete(
 	ctx context.Context, d *schema.ResourceData, m interface{},
 ) diag.Diagnostics {
 	config := m.(*Config)
+	if diags := config.checkWritable("delete", "njalla_record_txt"); diags != nil {
+		return diags
+	}
 
 	domain := d.Get("domain").(string)
 
-	err := gonjalla.RemoveRecord(config.Token, domain, d.Id())
-	if err != nil {
-		return diag.FromErr(err)
-	}
-
-	var diags diag.Diagnostics
-	return diags
+	return config.deleteRecord(ctx, d, domain, "TXT", "njalla_record_txt")
 }
 
 func resourceRecordTXTImport(
@@ -154,7 +195,7 @@ func resourceRecordTXTImport(
 
 	config := m.(*Config)
 
-	records, err := gonjalla.ListRecords(config.Token, domain)
+	records, err := config.listRecords(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"Reading records for domain %s failed: %s", domain, err.Error(),
@@ -165,9 +206,9 @@ func resourceRecordTXTImport(
 		if id == record.ID {
 			d.SetId(id)
 			d.Set("domain", domain)
-			d.Set("name", record.Name)
-			d.Set("ttl", record.TTL)
-			d.Set("content", record.Content)
+			setRecordName(d, domain, record.Name)
+			setRecordTTL(d, config, record.TTL)
+			setTXTContent(d, record.Content)
 
 			return []*schema.ResourceData{d}, nil
 		}
@@ -175,3 +216,52 @@ func resourceRecordTXTImport(
 
 	return nil, fmt.Errorf("Couldn't find record %s for domain %s", id, domain)
 }
+
+// setTXTContent sets `content` from the content of a record returned by the
+// API. The current value is kept if it has the same text, so a different
+// quoting or splitting doesn't show up as a change, otherwise the unquoted
+// text is used.
+func setTXTContent(d *schema.ResourceData, content string) {
+	text, err := dnsrecord.ParseTXT(content)
+	if err != nil {
+		d.Set("content", content)
+		return
+	}
+
+	current, err := dnsrecord.ParseTXT(d.Get("content").(string))
+	if err == nil && current == text {
+		return
+	}
+
+	d.Set("content", text)
+}
+
+// suppressEquivalentTXTContent ignores differences between contents with the
+// same text, whatever their quoting and splitting.
+func suppressEquivalentTXTContent(
+	k, old, new string, d *schema.ResourceData,
+) bool {
+	oldText, err := dnsrecord.ParseTXT(old)
+	if err != nil {
+		return false
+	}
+
+	newText, err := dnsrecord.ParseTXT(new)
+	if err != nil {
+		return false
+	}
+
+	return oldText == newText
+}
+
+// validateTXTContent checks the content of a TXT record can be parsed, and
+// that its text fits in a TXT record.
+func validateTXTContent(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	if err := dnsrecord.Validate(key, "TXT", val.(string)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return
+}
@@ -1,17 +1,30 @@
 package njalla
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"golang.org/x/net/dns/dnsmessage"
 
-	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
 )
 
+func init() {
+	resource.AddTestSweepers("njalla_record_txt", &resource.Sweeper{
+		Name: "njalla_record_txt",
+		F:    testSweepRecords("TXT"),
+	})
+}
+
 func TestAccRecordTXT_Create(t *testing.T) {
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
 
@@ -185,6 +198,238 @@ func TestAccRecordTXT_InvalidTTL(t *testing.T) {
 	})
 }
 
+func TestAccRecordTXT_DKIM(t *testing.T) {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	key2048 := testAccReadDKIMKey(t, 2048)
+	key4096 := testAccReadDKIMKey(t, 4096)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTXTDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordTXTDKIM(key2048),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordTXTExists("njalla_record_txt.test_dkim"),
+					testAccCheckRecordTXTStrings("njalla_record_txt.test_dkim"),
+					resource.TestCheckResourceAttr(
+						"njalla_record_txt.test_dkim", "content", key2048,
+					),
+				),
+			},
+			{
+				Config: testAccCheckRecordTXTDKIM(key4096),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordTXTStrings("njalla_record_txt.test_dkim"),
+					resource.TestCheckResourceAttr(
+						"njalla_record_txt.test_dkim", "content", key4096,
+					),
+				),
+			},
+			{
+				ResourceName:        "njalla_record_txt.test_dkim",
+				ImportStateIdPrefix: fmt.Sprintf("%s:", domain),
+				ImportState:         true,
+				ImportStateVerify:   true,
+			},
+		},
+	})
+}
+
+func TestAccRecordTXT_QuotedContent(t *testing.T) {
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTXTDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckRecordTXTQuotedContent(),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckRecordTXTExists(
+						"njalla_record_txt.test_quoted_content",
+					),
+					resource.TestCheckResourceAttr(
+						"njalla_record_txt.test_quoted_content",
+						"content",
+						`"testacc7-txt-" "quotedcontent-content"`,
+					),
+				),
+			},
+		},
+	})
+}
+
+func TestAccRecordTXT_QuotedStringTooLong(t *testing.T) {
+	expectedErr := regexp.MustCompile(
+		"expected every quoted string of TXT content to be at most 255 bytes",
+	)
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTXTDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccCheckRecordTXTQuotedStringTooLong(),
+				ExpectError: expectedErr,
+			},
+		},
+	})
+}
+
+func TestAccRecordTXT_WaitForPropagation(t *testing.T) {
+	if testAccMockServer == nil {
+		t.Skip("Needs a nameserver serving the domain, only run against the fake API")
+	}
+
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	nameserver := njallatest.NewDNSServer()
+	defer nameserver.Close()
+
+	name := "_acme-challenge.testacc10-txt-wait-name." + domain
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckRecordTXTDestroy,
+		Steps: []resource.TestStep{
+			{
+				// The nameserver only serves the record a while after
+				// it's created.
+				PreConfig: func() {
+					time.AfterFunc(time.Second, func() {
+						nameserver.Set(name, &dnsmessage.TXTResource{
+							TXT: []string{"testacc10-txt-wait-content"},
+						})
+					})
+				},
+				Config: testAccCheckRecordTXTWaitForPropagation(
+					nameserver.Addr, "testacc10-txt-wait-content",
+				),
+				Check: testAccCheckRecordTXTExists(
+					"njalla_record_txt.test_wait",
+				),
+			},
+			{
+				Config: testAccCheckRecordTXTWaitForPropagation(
+					nameserver.Addr, "testacc10-txt-wait-updated",
+				),
+				ExpectError: regexp.MustCompile(
+					"Timed out waiting for njalla_record_txt to propagate",
+				),
+			},
+		},
+	})
+}
+
+func TestTXTContentRoundTrip(t *testing.T) {
+	for _, bits := range []int{2048, 4096} {
+		key := testAccReadDKIMKey(t, bits)
+
+		formatted := dnsrecord.FormatTXT(key)
+		chunks := strings.Split(formatted, "\" \"")
+		if len(chunks) != (len(key)+254)/255 {
+			t.Fatalf(
+				"Expected %d bit key to be split in %d strings, got %d",
+				bits, (len(key)+254)/255, len(chunks),
+			)
+		}
+
+		text, err := dnsrecord.ParseTXT(formatted)
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+		if text != key {
+			t.Fatalf("%d bit key didn't round trip, got %q", bits, text)
+		}
+
+		if !suppressEquivalentTXTContent("content", key, formatted, nil) {
+			t.Fatalf("Expected split %d bit key to be suppressed", bits)
+		}
+
+		// The API could chunk the text differently, which must still be
+		// considered the same content.
+		var rechunked []string
+		for rest := key; rest != ""; {
+			size := 200
+			if len(rest) < size {
+				size = len(rest)
+			}
+			rechunked = append(rechunked, `"`+rest[:size]+`"`)
+			rest = rest[size:]
+		}
+		if !suppressEquivalentTXTContent(
+			"content", formatted, strings.Join(rechunked, " "), nil,
+		) {
+			t.Fatalf("Expected rechunked %d bit key to be suppressed", bits)
+		}
+	}
+}
+
+// testAccReadDKIMKey returns the TXT value of a DKIM record for an RSA key of
+// the given size, from testdata.
+func testAccReadDKIMKey(t *testing.T, bits int) string {
+	key, err := os.ReadFile(fmt.Sprintf("testdata/txt/dkim-%d.txt", bits))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(key)
+}
+
+// testAccCheckRecordTXTStrings checks the record stored by the API is made of
+// character-strings no longer than 255 bytes, with the same text as the
+// resource's content.
+func testAccCheckRecordTXTStrings(resource string) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		rs, ok := state.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("Not found: %s", resource)
+		}
+
+		config := testAccProvider.Meta().(*Config)
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"Error fetching the records data for domain %s: %s",
+				domain, err,
+			)
+		}
+
+		for _, record := range records {
+			if record.ID != rs.Primary.ID {
+				continue
+			}
+
+			text, err := dnsrecord.ParseTXT(record.Content)
+			if err != nil {
+				return fmt.Errorf("Record %s is invalid: %s", record.ID, err)
+			}
+			if text == record.Content && len(text) > dnsrecord.TXTMaxStringLength {
+				return fmt.Errorf(
+					"Record %s wasn't split in character-strings", record.ID,
+				)
+			}
+			if text != rs.Primary.Attributes["content"] {
+				return fmt.Errorf(
+					"Record %s text %q doesn't match content %q",
+					record.ID, text, rs.Primary.Attributes["content"],
+				)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf(
+			"Record %s doesn't exist for domain %s", rs.Primary.ID, domain,
+		)
+	}
+}
+
 func testAccCheckRecordTXTDestroy(s *terraform.State) error {
 	config := testAccProvider.Meta().(*Config)
 	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
@@ -194,7 +439,9 @@ func testAccCheckRecordTXTDestroy(s *terraform.State) error {
 			continue
 		}
 
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -227,7 +474,9 @@ func testAccCheckRecordTXTExists(resource string) resource.TestCheckFunc {
 
 		config := testAccProvider.Meta().(*Config)
 		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
-		records, err := gonjalla.ListRecords(config.Token, domain)
+		records, err := config.Client.ListRecords(
+			context.Background(), domain,
+		)
 		if err != nil {
 			return fmt.Errorf(
 				"Error fetching the records data for domain %s: %s",
@@ -317,3 +566,59 @@ resource njalla_record_txt test_invalid_ttl {
 }
 `, domain)
 }
+
+func testAccCheckRecordTXTDKIM(key string) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_txt test_dkim {
+  domain = %q
+  name = "testacc6-txt-dkim-name._domainkey"
+  ttl = 10800
+  content = %q
+}
+`, domain, key)
+}
+
+func testAccCheckRecordTXTQuotedContent() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_txt test_quoted_content {
+  domain = %q
+  name = "testacc7-txt-quotedcontent-name"
+  ttl = 10800
+  content = "\"testacc7-txt-\" \"quotedcontent-content\""
+}
+`, domain)
+}
+
+func testAccCheckRecordTXTQuotedStringTooLong() string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_txt test_quoted_string_too_long {
+  domain = %q
+  name = "testacc8-txt-quotedstringtoolong-name"
+  ttl = 10800
+  content = "\"%s\""
+}
+`, domain, strings.Repeat("a", 256))
+}
+
+func testAccCheckRecordTXTWaitForPropagation(
+	nameserver string, content string,
+) string {
+	domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+	return fmt.Sprintf(`
+resource njalla_record_txt test_wait {
+  domain = %q
+  name = "_acme-challenge.testacc10-txt-wait-name"
+  ttl = 60
+  content = %q
+
+  wait_for_propagation {
+    nameservers = [%q]
+    timeout = "3s"
+    poll_interval = "100ms"
+  }
+}
+`, domain, content, nameserver)
+}
@@ -0,0 +1,193 @@
+package njalla
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	"github.com/Sighery/gonjalla"
+
+	"github.com/Sighery/terraform-provider-njalla/internal/api"
+	"github.com/Sighery/terraform-provider-njalla/internal/njallatest"
+)
+
+// testAccRecordPrefix is the prefix of the name of every record created by
+// the acceptance tests. Sweepers only ever remove records matching it.
+const testAccRecordPrefix = "testacc"
+
+// testSweepRecords returns a sweeper removing every record of the given
+// type left behind by acceptance tests on `NJALLA_TESTACC_DOMAIN`. Njalla has
+// no regions, so the region given to `-sweep` is ignored.
+func testSweepRecords(recordType string) resource.SweeperFunc {
+	return func(region string) error {
+		token := os.Getenv("NJALLA_API_TOKEN")
+		if token == "" {
+			return fmt.Errorf("NJALLA_API_TOKEN must be set for sweepers")
+		}
+		domain := os.Getenv("NJALLA_TESTACC_DOMAIN")
+		if domain == "" {
+			return fmt.Errorf("NJALLA_TESTACC_DOMAIN must be set for sweepers")
+		}
+
+		client := api.NewClient(token)
+		if endpoint := os.Getenv("NJALLA_API_ENDPOINT"); endpoint != "" {
+			client.Endpoint = endpoint
+		}
+
+		return testSweepDomainRecords(client, domain, recordType)
+	}
+}
+
+// testSweepName reports whether a record name was created by the acceptance
+// tests: either it starts with `testAccRecordPrefix`, or it's an ownership
+// marker or ACME challenge whose remaining labels do.
+func testSweepName(name string) bool {
+	name = strings.ToLower(name)
+
+	labels := strings.SplitN(name, ".", 2)
+	if len(labels) == 2 &&
+		(strings.HasPrefix(labels[0], ownerMarkerPrefix) ||
+			labels[0] == "_acme-challenge") {
+		name = labels[1]
+	}
+
+	return strings.HasPrefix(name, testAccRecordPrefix)
+}
+
+// testSweepDomainRecords removes every record of the given type in a domain
+// for which `testSweepName` holds. It carries on after a failed
+// removal, and returns an error listing every record it couldn't remove.
+func testSweepDomainRecords(
+	client *api.Client, domain string, recordType string,
+) error {
+	ctx := context.Background()
+
+	records, err := client.ListRecords(ctx, domain)
+	if err != nil {
+		return fmt.Errorf(
+			"Error fetching the records data for domain %s: %s", domain, err,
+		)
+	}
+
+	var failed []string
+	for _, record := range records {
+		if record.Type != recordType {
+			continue
+		}
+		if !testSweepName(record.Name) {
+			continue
+		}
+
+		log.Printf(
+			"[INFO] Sweeping %s record %s (%s) in domain %s",
+			record.Type, record.Name, record.ID, domain,
+		)
+
+		if err := client.RemoveRecord(ctx, domain, record.ID); err != nil {
+			log.Printf("[ERROR] Failed to sweep record %s: %s", record.ID, err)
+			failed = append(failed, record.ID)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf(
+			"Failed to sweep %s records %s in domain %s",
+			recordType, strings.Join(failed, ", "), domain,
+		)
+	}
+
+	return nil
+}
+
+func TestSweepDomainRecords(t *testing.T) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "testacc1-a-create-name", Content: "1.1.1.1", TTL: 10800,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "www", Content: "1.1.1.2", TTL: 10800,
+	})
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "TXT", Name: "testacc1-txt-create-name", Content: "txt", TTL: 10800,
+	})
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	if err := testSweepDomainRecords(client, "testing.com", "A"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	records := server.Records("testing.com")
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records left, got %v", records)
+	}
+	for _, record := range records {
+		if record.Type == "A" && record.Name != "www" {
+			t.Fatalf("Record %v should have been swept", record)
+		}
+	}
+}
+
+func TestSweepDomainRecordsFailure(t *testing.T) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	server.AddRecord("testing.com", gonjalla.Record{
+		Type: "A", Name: "testacc1-a-create-name", Content: "1.1.1.1", TTL: 10800,
+	})
+	server.FailNext("remove-record", 500, "Internal error")
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	if err := testSweepDomainRecords(client, "testing.com", "A"); err == nil {
+		t.Fatal("Unexpected success")
+	}
+}
+
+func TestSweepDomainRecordsMarkers(t *testing.T) {
+	server := njallatest.NewServer("test-token")
+	t.Cleanup(server.Close)
+	server.AddDomain("testing.com")
+
+	for _, name := range []string{
+		"_njalla-owner-a.testacc1-a-create-name",
+		"_acme-challenge.testacc1-acme-create",
+		"_acme-challenge.www",
+		"_njalla-owner-a.www",
+		"_njalla-owner-ns",
+	} {
+		server.AddRecord("testing.com", gonjalla.Record{
+			Type: "TXT", Name: name, Content: "txt", TTL: 10800,
+		})
+	}
+
+	client := api.NewClient("test-token")
+	client.Endpoint = server.URL
+
+	if err := testSweepDomainRecords(client, "testing.com", "TXT"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	var names []string
+	for _, record := range server.Records("testing.com") {
+		names = append(names, record.Name)
+	}
+	sort.Strings(names)
+
+	expected := []string{"_acme-challenge.www", "_njalla-owner-a.www", "_njalla-owner-ns"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Fatalf("Expected %v left, got %v", expected, names)
+	}
+}
@@ -0,0 +1,330 @@
+package njalla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/Sighery/gonjalla"
+	"github.com/Sighery/terraform-provider-njalla/internal/dnsrecord"
+)
+
+// TTLs can be given as a number of seconds, like `3600`, or as a duration
+// string, like `1h`. Only the values in `gonjalla.ValidTTL` are accepted by
+// Njalla. By default any other value fails at plan time, listing the nearest
+// valid TTLs. With the provider's `ttl_rounding = "nearest"`, it's rounded to
+// the nearest valid TTL instead, with a warning when applied.
+//
+// The `ttl` attribute keeps the value as written in the configuration, and
+// is compared by the number of seconds it resolves to.
+
+const (
+	// defaultTTL is the provider's `default_ttl` when not configured.
+	defaultTTL = 10800
+
+	ttlRoundingNone    = "none"
+	ttlRoundingNearest = "nearest"
+)
+
+// ttlSchema returns the schema of the `ttl` attribute shared by every record
+// resource.
+func ttlSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		Description: "TTL for the record, in seconds or as a duration like " +
+			"1h. Defaults to the provider's default_ttl.",
+		ValidateFunc:     validateTTLSyntax,
+		DiffSuppressFunc: suppressEquivalentTTL,
+	}
+}
+
+// withTTLStateUpgrader sets up the state upgrade of a record resource that
+// existed before `ttl` accepted durations. Its `ttl` was stored as a number
+// in version 0 of the resource's schema, and is a string since version 1.
+func withTTLStateUpgrader(r *schema.Resource) *schema.Resource {
+	v0 := recordSchemaV0()
+	if _, ok := r.Schema["priority"]; ok {
+		v0["priority"] = &schema.Schema{
+			Type:     schema.TypeInt,
+			Required: true,
+		}
+	}
+
+	r.SchemaVersion = 1
+	r.StateUpgraders = []schema.StateUpgrader{
+		{
+			Version: 0,
+			Type:    (&schema.Resource{Schema: v0}).CoreConfigSchema().ImpliedType(),
+			Upgrade: upgradeTTLStateV0,
+		},
+	}
+
+	return r
+}
+
+// recordSchemaV0 returns the attributes shared by the record resources in
+// version 0 of their schema. It's frozen, so attributes added since don't
+// change the state it describes. MX records also had a `priority`.
+func recordSchemaV0() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"domain": {
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: true,
+		},
+		"name": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"ttl": {
+			Type:     schema.TypeInt,
+			Required: true,
+		},
+		"content": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+	}
+}
+
+// upgradeTTLStateV0 turns the numeric `ttl` of a version 0 state into a
+// string.
+func upgradeTTLStateV0(
+	ctx context.Context, rawState map[string]interface{}, meta interface{},
+) (map[string]interface{}, error) {
+	if rawState == nil {
+		return rawState, nil
+	}
+
+	switch ttl := rawState["ttl"].(type) {
+	case float64:
+		rawState["ttl"] = strconv.FormatFloat(ttl, 'f', -1, 64)
+	case json.Number:
+		rawState["ttl"] = ttl.String()
+	case int:
+		rawState["ttl"] = strconv.Itoa(ttl)
+	}
+
+	return rawState, nil
+}
+
+// parseTTL returns the number of seconds of a TTL given as an integer, or
+// as a duration string like `1h` or `1h30m` as accepted by
+// `time.ParseDuration`.
+func parseTTL(v string) (int, error) {
+	v = strings.TrimSpace(v)
+
+	if seconds, err := dnsrecord.ParseUnsigned(v); err == nil {
+		return seconds, nil
+	}
+
+	duration, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"expected ttl to be a number of seconds or a duration like 1h, "+
+				"got: %s",
+			v,
+		)
+	}
+
+	if duration <= 0 || duration%time.Second != 0 {
+		return 0, fmt.Errorf(
+			"expected ttl to be a positive whole number of seconds, got: %s",
+			v,
+		)
+	}
+
+	return int(duration / time.Second), nil
+}
+
+// formatTTL returns a TTL in seconds along with its duration, like
+// `3600 (1h0m0s)`.
+func formatTTL(ttl int) string {
+	return fmt.Sprintf("%d (%s)", ttl, time.Duration(ttl)*time.Second)
+}
+
+// invalidTTLError describes a TTL Njalla doesn't accept, listing the nearest
+// valid ones.
+func invalidTTLError(v string, ttl int) error {
+	var nearest []string
+
+	lower, upper := dnsrecord.NearestTTLs(ttl)
+	if lower != 0 {
+		nearest = append(nearest, formatTTL(lower))
+	}
+	if upper != 0 && upper != lower {
+		nearest = append(nearest, formatTTL(upper))
+	}
+
+	return fmt.Errorf(
+		"expected ttl to be one of %v, got %s. The nearest valid TTLs are "+
+			"%s. Set ttl_rounding = \"nearest\" in the provider to round "+
+			"automatically",
+		gonjalla.ValidTTL, v, strings.Join(nearest, " and "),
+	)
+}
+
+// resolveTTL returns the TTL to send to the API for a configured value,
+// rounding it if allowed. `rounded` reports whether it was.
+func (c *Config) resolveTTL(v string) (ttl int, rounded bool, err error) {
+	if v == "" {
+		return c.defaultTTL(), false, nil
+	}
+
+	ttl, err = parseTTL(v)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if dnsrecord.IsValidTTL(ttl) {
+		return ttl, false, nil
+	}
+
+	if c != nil && c.TTLRounding == ttlRoundingNearest {
+		return dnsrecord.RoundTTL(ttl), true, nil
+	}
+
+	return 0, false, invalidTTLError(v, ttl)
+}
+
+// defaultTTL returns the provider's `default_ttl`.
+func (c *Config) defaultTTL() int {
+	if c == nil || c.DefaultTTL == 0 {
+		return defaultTTL
+	}
+
+	return c.DefaultTTL
+}
+
+// recordTTL returns the TTL to send to the API for the record described by
+// `d`. A warning diagnostic is returned if it was rounded.
+func (c *Config) recordTTL(
+	d *schema.ResourceData, resource string,
+) (int, diag.Diagnostics) {
+	v := d.Get("ttl").(string)
+
+	ttl, rounded, err := c.resolveTTL(v)
+	if err != nil {
+		return 0, diag.FromErr(err)
+	}
+
+	if !rounded {
+		return ttl, nil
+	}
+
+	return ttl, diag.Diagnostics{
+		{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Rounded ttl of %s", resource),
+			Detail: fmt.Sprintf(
+				"Njalla doesn't accept a ttl of %s, so the nearest valid "+
+					"one, %s, was used instead.",
+				v, formatTTL(ttl),
+			),
+		},
+	}
+}
+
+// setRecordTTL sets `ttl` from the TTL of a record returned by the API. The
+// current value is kept if it resolves to that TTL, so writing it as a
+// duration or a rounded value doesn't show up as a change.
+func setRecordTTL(d *schema.ResourceData, config *Config, ttl int) {
+	current := d.Get("ttl").(string)
+	if current != "" {
+		if resolved, _, err := config.resolveTTL(current); err == nil &&
+			resolved == ttl {
+			return
+		}
+	}
+
+	d.Set("ttl", strconv.Itoa(ttl))
+}
+
+// suppressEquivalentTTL ignores differences between TTLs resolving to the
+// same number of seconds, like `3600` and `1h`.
+func suppressEquivalentTTL(k, old, new string, d *schema.ResourceData) bool {
+	oldTTL, err := parseTTL(old)
+	if err != nil {
+		return false
+	}
+
+	newTTL, err := parseTTL(new)
+	if err != nil {
+		return false
+	}
+
+	return oldTTL == newTTL
+}
+
+// validateTTLSyntax checks a TTL can be parsed. Whether Njalla accepts it
+// depends on the provider's `ttl_rounding`, so it's checked by
+// `customizeRecordTTL`.
+func validateTTLSyntax(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	if _, err := parseTTL(val.(string)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return
+}
+
+// validateDefaultTTL checks the provider's `default_ttl` is a TTL Njalla
+// accepts.
+func validateDefaultTTL(
+	val interface{}, key string,
+) (warns []string, errs []error) {
+	v := val.(string)
+
+	ttl, err := parseTTL(v)
+	if err != nil {
+		errs = append(errs, err)
+		return
+	}
+
+	if !dnsrecord.IsValidTTL(ttl) {
+		errs = append(errs, invalidTTLError(v, ttl))
+	}
+
+	return
+}
+
+// customizeRecordTTL is a `CustomizeDiff` planning the provider's
+// `default_ttl` when `ttl` isn't configured, and failing the plan if the
+// configured TTL isn't accepted by Njalla and can't be rounded.
+func customizeRecordTTL(
+	ctx context.Context, d *schema.ResourceDiff, m interface{},
+) error {
+	config, _ := m.(*Config)
+
+	if d.GetRawConfig().GetAttr("ttl").IsNull() {
+		current := d.Get("ttl").(string)
+		if ttl, err := parseTTL(current); err == nil &&
+			ttl == config.defaultTTL() {
+			return nil
+		}
+
+		return d.SetNew("ttl", strconv.Itoa(config.defaultTTL()))
+	}
+
+	if !d.NewValueKnown("ttl") {
+		return nil
+	}
+
+	v := d.Get("ttl").(string)
+	if _, err := parseTTL(v); err != nil {
+		// Already reported by `validateTTLSyntax`.
+		return nil
+	}
+
+	_, _, err := config.resolveTTL(v)
+	return err
+}
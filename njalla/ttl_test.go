@@ -0,0 +1,206 @@
+package njalla
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	ctymsgpack "github.com/hashicorp/go-cty/cty/msgpack"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestParseTTL(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int
+		valid    bool
+	}{
+		{"3600", 3600, true},
+		{" 300 ", 300, true},
+		{"1h", 3600, true},
+		{"15m", 900, true},
+		{"1h30m", 5400, true},
+		{"24h", 86400, true},
+		{"", 0, false},
+		{"0s", 0, false},
+		{"-1h", 0, false},
+		{"1.5s", 0, false},
+		{"1500ms", 0, false},
+		{"one hour", 0, false},
+	}
+
+	for _, c := range cases {
+		ttl, err := parseTTL(c.input)
+		if c.valid && err != nil {
+			t.Errorf("parseTTL(%q) returned an error: %s", c.input, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("parseTTL(%q) = %d, expected an error", c.input, ttl)
+		} else if ttl != c.expected {
+			t.Errorf("parseTTL(%q) = %d, expected %d", c.input, ttl, c.expected)
+		}
+	}
+}
+
+func TestParseTTLUnsigned(t *testing.T) {
+	for input, expected := range map[string]int{"0": 0, "255": 255, "007": 7} {
+		result, err := parseTTL(input)
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+		if result != expected {
+			t.Fatalf("Expected %d for %q, got %d", expected, input, result)
+		}
+	}
+}
+
+func TestParseTTLSigned(t *testing.T) {
+	for _, input := range []string{"", "+1", "-0", "1a", "1 1"} {
+		if _, err := parseTTL(input); err == nil {
+			t.Fatalf("Unexpected success for %q", input)
+		}
+	}
+}
+
+func TestResolveTTL(t *testing.T) {
+	config := &Config{DefaultTTL: 3600}
+
+	if ttl, _, err := config.resolveTTL(""); err != nil || ttl != 3600 {
+		t.Errorf("Expected the default TTL 3600, got %d, %v", ttl, err)
+	}
+
+	_, _, err := config.resolveTTL("999")
+	if err == nil {
+		t.Fatal("Expected an error for a TTL of 999")
+	}
+	if !strings.Contains(err.Error(), "900 (15m0s) and 3600 (1h0m0s)") {
+		t.Errorf("Expected the nearest valid TTLs in the error, got: %s", err)
+	}
+
+	config.TTLRounding = ttlRoundingNearest
+	ttl, rounded, err := config.resolveTTL("999")
+	if err != nil || !rounded || ttl != 900 {
+		t.Errorf("Expected 999 rounded to 900, got %d, %t, %v", ttl, rounded, err)
+	}
+}
+
+func TestResolveTTLRounding(t *testing.T) {
+	config := &Config{TTLRounding: ttlRoundingNearest}
+
+	cases := map[int]int{
+		1:      60,
+		60:     60,
+		179:    60,
+		180:    300,
+		1000:   900,
+		2249:   900,
+		2250:   3600,
+		100000: 86400,
+	}
+
+	for ttl, expected := range cases {
+		rounded, _, err := config.resolveTTL(strconv.Itoa(ttl))
+		if err != nil || rounded != expected {
+			t.Errorf("resolveTTL(%d) = %d, %v, expected %d", ttl, rounded, err, expected)
+		}
+	}
+}
+
+func TestUpgradeTTLStateV0(t *testing.T) {
+	cases := []struct {
+		ttl      interface{}
+		expected interface{}
+	}{
+		{float64(10800), "10800"},
+		{json.Number("3600"), "3600"},
+		{"300", "300"},
+		{nil, nil},
+	}
+
+	for _, c := range cases {
+		state := map[string]interface{}{"id": "1", "ttl": c.ttl}
+
+		upgraded, err := upgradeTTLStateV0(context.Background(), state, nil)
+		if err != nil {
+			t.Fatalf("Upgrading ttl %v failed: %s", c.ttl, err)
+		}
+		if upgraded["ttl"] != c.expected {
+			t.Errorf(
+				"Upgrading ttl %#v gave %#v, expected %#v",
+				c.ttl, upgraded["ttl"], c.expected,
+			)
+		}
+	}
+}
+
+func TestRecordResourcesTTLStateUpgrade(t *testing.T) {
+	for name, r := range Provider().ResourcesMap {
+		if len(r.StateUpgraders) == 0 {
+			// Resources added after ttl became a string.
+			continue
+		}
+
+		if r.SchemaVersion != 1 {
+			t.Errorf("Expected %s at schema version 1, got %d", name, r.SchemaVersion)
+		}
+
+		v0 := r.StateUpgraders[0].Type
+		if !v0.AttributeType("ttl").Equals(cty.Number) {
+			t.Errorf("Expected the version 0 ttl of %s to be a number", name)
+		}
+
+		expected := []string{"content", "domain", "id", "name", "ttl"}
+		if name == "njalla_record_mx" {
+			expected = []string{"content", "domain", "id", "name", "priority", "ttl"}
+		}
+		var attributes []string
+		for attribute := range v0.AttributeTypes() {
+			attributes = append(attributes, attribute)
+		}
+		sort.Strings(attributes)
+		if strings.Join(attributes, " ") != strings.Join(expected, " ") {
+			t.Errorf(
+				"Expected the version 0 state of %s to have %v, got %v",
+				name, expected, attributes,
+			)
+		}
+	}
+}
+
+func TestRecordTTLStateUpgradeFromNumber(t *testing.T) {
+	provider := Provider()
+	server := schema.NewGRPCProviderServer(provider)
+
+	resp, err := server.UpgradeResourceState(
+		context.Background(),
+		&tfprotov5.UpgradeResourceStateRequest{
+			TypeName: "njalla_record_a",
+			Version:  0,
+			RawState: &tfprotov5.RawState{JSON: []byte(`{
+				"id": "1234", "domain": "testing.com", "name": "www",
+				"ttl": 3600, "content": "1.1.1.1"
+			}`)},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range resp.Diagnostics {
+		t.Fatalf("Unexpected diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	state, err := ctymsgpack.Unmarshal(
+		resp.UpgradedState.MsgPack,
+		provider.ResourcesMap["njalla_record_a"].CoreConfigSchema().ImpliedType(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := state.GetAttr("ttl"); !ttl.RawEquals(cty.StringVal("3600")) {
+		t.Fatalf("Expected the ttl upgraded to \"3600\", got %#v", ttl)
+	}
+}